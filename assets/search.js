@@ -0,0 +1,50 @@
+// Completes the search form with suggestions while typing. Without JavaScript the form works as before.
+(function () {
+  var input = document.querySelector('.search-form input[name="text"]');
+  var list = document.getElementById('search-suggestions');
+  if (!input || !list || !window.fetch) {
+    return;
+  }
+
+  var timer = null;
+  var controller = null;
+
+  function show(suggestions) {
+    list.innerHTML = '';
+
+    suggestions.titles.concat(suggestions.terms).forEach(function (value) {
+      var option = document.createElement('option');
+      option.value = value;
+      list.appendChild(option);
+    });
+  }
+
+  input.addEventListener('input', function () {
+    clearTimeout(timer);
+
+    var prefix = input.value.trim();
+    if (prefix === '') {
+      list.innerHTML = '';
+      return;
+    }
+
+    // wait for a short pause in typing and cancel the request for the previous prefix.
+    timer = setTimeout(function () {
+      if (controller && window.AbortController) {
+        controller.abort();
+      }
+      controller = window.AbortController ? new AbortController() : null;
+
+      fetch('/api/v1/jokes/search/suggest?prefix=' + encodeURIComponent(prefix), {
+        signal: controller ? controller.signal : undefined
+      })
+        .then(function (response) { return response.ok ? response.json() : null; })
+        .then(function (suggestions) {
+          if (suggestions && suggestions.prefix === input.value.trim()) {
+            show(suggestions);
+          }
+        })
+        .catch(function () {});
+    }, 150);
+  });
+})();
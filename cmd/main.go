@@ -6,10 +6,19 @@ import (
 	"strconv"
 
 	"github.com/DanilLagunov/jokes-api/pkg/api"
+	"github.com/DanilLagunov/jokes-api/pkg/cache"
 	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
 	"github.com/DanilLagunov/jokes-api/pkg/config"
-	"github.com/DanilLagunov/jokes-api/pkg/storage/mongodb"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/cached"
 	"github.com/DanilLagunov/jokes-api/pkg/views"
+
+	// register the cache and storage drivers selected by CACHE_DRIVER and STORAGE_DRIVER.
+	_ "github.com/DanilLagunov/jokes-api/pkg/cache/rediscache"
+	_ "github.com/DanilLagunov/jokes-api/pkg/storage/file-storage"
+	_ "github.com/DanilLagunov/jokes-api/pkg/storage/mongodb"
+	_ "github.com/DanilLagunov/jokes-api/pkg/storage/postgres"
+	_ "github.com/DanilLagunov/jokes-api/pkg/storage/sqlite"
 )
 
 func main() {
@@ -18,18 +27,37 @@ func main() {
 		log.Fatal(err)
 	}
 
-	storage, err := mongodb.NewDatabase(cfg.DbURI, cfg.DbName, cfg.JokesCollection)
+	storage, err := storage.Open(cfg.StorageDriver, cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// the pages are cached by every replica itself, a zero expiration disables the cache.
+	if cfg.PageCacheExpiration > 0 {
+		pages, err := memcache.NewBoundedMemCache[cached.Page](cfg.PageCacheExpiration, cfg.CacheCleanupInterval,
+			memcache.Limits{MaxEntries: cfg.PageCacheMaxEntries, Policy: memcache.Policy(cfg.CacheEviction)})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		storage = cached.NewStorage(storage, pages)
+	}
+
 	template := views.NewTemptale("./templates/")
 
-	cache := memcache.NewMemCache(cfg.CacheDefaultExpiration, cfg.CacheCleanupInterval)
+	cache, err := cache.Open(cfg.CacheDriver, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	proxies, err := api.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	server := http.Server{
 		Addr:              ":" + strconv.Itoa(cfg.Port),
-		Handler:           api.NewHandler(storage, template, cache),
+		Handler:           api.NewHandler(storage, template, cache, api.WithTrustedProxies(proxies)),
 		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
 		ReadTimeout:       cfg.ReadTimeout,
 		WriteTimeout:      cfg.WriteTimeout,
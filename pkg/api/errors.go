@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+)
+
+// Error codes are stable identifiers of the errors returned to clients.
+const (
+	codeMissingParameter  = "missing_parameter"
+	codeInvalidPagination = "invalid_pagination"
+	codeInvalidJoke       = "invalid_joke"
+	codeInvalidSearch     = "invalid_search"
+	codeInvalidPattern    = "invalid_pattern"
+	codeInvalidFilter     = "invalid_filter"
+	codeInvalidCursor     = "invalid_cursor"
+	codeInvalidSort       = "invalid_sort"
+	codeJokeNotFound      = "joke_not_found"
+	codeAlreadyVoted      = "already_voted"
+	codeRouteNotFound     = "route_not_found"
+	codeMethodNotAllowed  = "method_not_allowed"
+	codeTimeout           = "timeout"
+	codeBackendFailure    = "backend_failure"
+)
+
+var errAlreadyVoted = &requestError{
+	status: http.StatusConflict,
+	code:   codeAlreadyVoted,
+	detail: "you have already voted for this joke",
+}
+
+// requestError describes an error caused by the request, its detail is safe to show to the client.
+type requestError struct {
+	status int
+	code   string
+	detail string
+}
+
+func newRequestError(code, detail string) *requestError {
+	return &requestError{status: http.StatusBadRequest, code: code, detail: detail}
+}
+
+func (e *requestError) Error() string {
+	return e.detail
+}
+
+type timeoutError interface {
+	Timeout() bool
+}
+
+// problemFor maps err to the problem reported to the client. Details of backend failures are
+// never exposed, they are logged instead.
+func problemFor(err error) views.Problem {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return views.NewProblem(reqErr.status, reqErr.code, reqErr.detail)
+	}
+
+	if errors.Is(err, storage.ErrJokeNotFound) {
+		return views.NewProblem(http.StatusNotFound, codeJokeNotFound, storage.ErrJokeNotFound.Error())
+	}
+
+	if errors.Is(err, storage.ErrUnknownSearchMode) {
+		return views.NewProblem(http.StatusBadRequest, codeInvalidSearch, "mode has to be plain, regex, text or fuzzy")
+	}
+
+	if errors.Is(err, storage.ErrInvalidPattern) {
+		return views.NewProblem(http.StatusBadRequest, codeInvalidPattern, err.Error())
+	}
+
+	if errors.Is(err, storage.ErrInvalidSort) {
+		return views.NewProblem(http.StatusBadRequest, codeInvalidSort, "the listing does not support the sort")
+	}
+
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		return views.NewProblem(http.StatusBadRequest, codeInvalidCursor, "cursor is not valid")
+	}
+
+	log.Print(err)
+
+	var timeoutErr timeoutError
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeoutErr) && timeoutErr.Timeout()) {
+		return views.NewProblem(http.StatusGatewayTimeout, codeTimeout, "the storage did not respond in time")
+	}
+
+	return views.NewProblem(http.StatusInternalServerError, codeBackendFailure, "the storage failed to handle the request")
+}
+
+func (h Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := problemFor(err)
+	problem.Instance = r.URL.RequestURI()
+
+	h.template.RenderProblem(w, r, problem)
+}
+
+func (h Handler) notFound(w http.ResponseWriter, r *http.Request) {
+	h.writeError(w, apiRequest(r), &requestError{
+		status: http.StatusNotFound,
+		code:   codeRouteNotFound,
+		detail: "the requested page does not exist",
+	})
+}
+
+func (h Handler) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	h.writeError(w, apiRequest(r), &requestError{
+		status: http.StatusMethodNotAllowed,
+		code:   codeMethodNotAllowed,
+		detail: r.Method + " is not allowed for the requested page",
+	})
+}
+
+// apiRequest forces JSON responses for /api requests that did not match any route,
+// since the middleware of the api subrouter only runs for matched routes.
+func apiRequest(r *http.Request) *http.Request {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return r.WithContext(views.WithFormat(r.Context(), views.FormatJSON))
+	}
+
+	return r
+}
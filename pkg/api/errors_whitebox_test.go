@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingStorage fails every listing with the given error.
+type failingStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s failingStorage) GetJokes(ctx context.Context, skip, seed int, filter storage.Filter) ([]models.Joke, int, error) {
+	return nil, 0, s.err
+}
+
+func TestErrorResponses(t *testing.T) {
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+
+	tests := []struct {
+		Err    error
+		URL    string
+		Status int
+		Code   string
+		Detail string
+	}{
+		{
+			URL:    "/api/v1/jokes?skip=abc",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidPagination,
+			Detail: "skip is not a valid number",
+		},
+		{
+			URL:    "/api/v1/jokes/unknown",
+			Status: http.StatusNotFound,
+			Code:   codeJokeNotFound,
+			Detail: "joke not found",
+		},
+		{
+			URL:    "/api/v1/jokes/search?text=horse&mode=soundex",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidSearch,
+			Detail: "mode has to be plain, regex, text or fuzzy",
+		},
+		{
+			URL:    "/api/v1/jokes/search?text=%28a%2B%29%2B%24&mode=regex",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidPattern,
+			Detail: "invalid search pattern: nested repetitions are not allowed",
+		},
+		{
+			URL:    "/api/v1/jokes/funniest?min_score=high",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidFilter,
+			Detail: "min_score is not a valid number",
+		},
+		{
+			URL:    "/api/v1/jokes?min_score=5&max_score=1",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidFilter,
+			Detail: "invalid filter: the minimal score is greater than the maximal score",
+		},
+		{
+			URL:    "/api/v1/jokes/search?text=horse&field=score",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidFilter,
+			Detail: "field has to be title or body",
+		},
+		{
+			URL:    "/api/v1/jokes?cursor=%21%21",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidCursor,
+			Detail: "cursor is not valid",
+		},
+		{
+			URL:    "/api/v1/jokes/funniest?cursor=MDptaXNzaW5n",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidCursor,
+			Detail: "cursor is not valid",
+		},
+		{
+			URL:    "/api/v1/jokes?sort=id",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidSort,
+			Detail: "sort has to be created, score, -score, title, random or relevance",
+		},
+		{
+			URL:    "/api/v1/jokes/funniest?sort=relevance",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidSort,
+			Detail: "the listing does not support the sort",
+		},
+		{
+			URL:    "/api/v1/jokes?sort=random&rand_seed=x",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidSort,
+			Detail: "rand_seed is not a valid number",
+		},
+		{
+			URL:    "/api/v1/jokes/random?rand_seed=1.5",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidSort,
+			Detail: "rand_seed is not a valid number",
+		},
+		{
+			URL:    "/api/v1/jokes?sort=title&cursor=MDptaXNzaW5n",
+			Status: http.StatusBadRequest,
+			Code:   codeInvalidCursor,
+			Detail: "the title order does not support cursors",
+		},
+		{
+			URL:    "/api/v1/unknown",
+			Status: http.StatusNotFound,
+			Code:   codeRouteNotFound,
+		},
+		{
+			Err:    errors.New("connection(localhost:27017) socket was unexpectedly closed"),
+			URL:    "/api/v1/jokes",
+			Status: http.StatusInternalServerError,
+			Code:   codeBackendFailure,
+			Detail: "the storage failed to handle the request",
+		},
+		{
+			Err:    context.DeadlineExceeded,
+			URL:    "/jokes?format=json",
+			Status: http.StatusGatewayTimeout,
+			Code:   codeTimeout,
+			Detail: "the storage did not respond in time",
+		},
+	}
+
+	for _, tc := range tests {
+		storage := failingStorage{newTempFileStorage(t), tc.Err}
+		h := NewHandler(storage, template, cache)
+		t.Cleanup(h.Close)
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.URL, nil))
+
+		require.EqualValues(t, tc.Status, recorder.Code, tc.URL)
+		assert.EqualValues(t, "application/problem+json; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+		var problem views.Problem
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+
+		assert.EqualValues(t, tc.Status, problem.Status)
+		assert.EqualValues(t, http.StatusText(tc.Status), problem.Title)
+		assert.EqualValues(t, tc.Code, problem.Code)
+		assert.EqualValues(t, tc.URL, problem.Instance)
+		if tc.Detail != "" {
+			assert.EqualValues(t, tc.Detail, problem.Detail)
+		}
+	}
+}
+
+func TestErrorPage(t *testing.T) {
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(failingStorage{newTempFileStorage(t), errors.New("server selection error")}, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/jokes", nil))
+
+	assert.EqualValues(t, http.StatusInternalServerError, recorder.Code)
+	assert.EqualValues(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "500 Internal Server Error")
+	assert.NotContains(t, recorder.Body.String(), "server selection error")
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/jokes/unknown", nil))
+
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+	assert.True(t, strings.Contains(recorder.Body.String(), "joke not found"))
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/DanilLagunov/jokes-api/pkg/cache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
 	"github.com/DanilLagunov/jokes-api/pkg/storage"
 	"github.com/DanilLagunov/jokes-api/pkg/views"
 	"github.com/gorilla/mux"
@@ -16,20 +18,44 @@ type Handler struct {
 	Router   *mux.Router
 	storage  storage.Storage
 	template views.Template
-	cache    cache.Cache
+	cache    cache.Cache[models.Joke]
+	votes    *voteRegistry
+	suggest  *suggestIndex
+	proxies  []*net.IPNet
+}
+
+// Option configures the Handler created by NewHandler.
+type Option func(h *Handler)
+
+// WithTrustedProxies makes the handler take the address of a voting client from the X-Real-IP header,
+// when the request comes from one of the proxies.
+func WithTrustedProxies(proxies []*net.IPNet) Option {
+	return func(h *Handler) {
+		h.proxies = proxies
+	}
 }
 
 // NewHandler creating a new Handler object.
-func NewHandler(s storage.Storage, t views.Template, c cache.Cache) *Handler {
+func NewHandler(s storage.Storage, t views.Template, c cache.Cache[models.Joke], opts ...Option) *Handler {
 	h := &Handler{
 		storage:  s,
 		template: t,
 		cache:    c,
+		votes:    newVoteRegistry(),
+		suggest:  newSuggestIndex(s),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
 	h.Router = h.initRoutes()
 	return h
 }
 
+// Close stops the background work of the handler, it does not close the storage or the cache.
+func (h *Handler) Close() {
+	h.suggest.close()
+}
+
 func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	start := time.Now()
 
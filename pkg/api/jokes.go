@@ -2,63 +2,101 @@ package api
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
+	"html/template"
 	"log"
+	"math/rand"
+	"mime"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"time"
 
+	"github.com/DanilLagunov/jokes-api/pkg/models"
 	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/cached"
 	"github.com/DanilLagunov/jokes-api/pkg/views"
 	"github.com/gorilla/mux"
 )
 
 const requestTimeout time.Duration = time.Second * 2
 
+// suggestionSample is the number of fuzzy search results the "did you mean" suggestions are made of.
+const suggestionSample = 20
+
+// jokeRequest holds the joke fields sent by the client, nil fields were not sent.
+type jokeRequest struct {
+	Title *string `json:"title"`
+	Body  *string `json:"body"`
+}
+
 func (h Handler) getJokes(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 	defer cancel()
 
 	skip, limit, err := getPaginationParams(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		h.writeError(w, r, err)
+		return
 	}
 
-	jokes, amount, err := h.storage.GetJokes(ctx, skip, limit)
+	filter, err := getFilterParams(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-
-		_, err := w.Write([]byte(err.Error()))
-		logResponseWriteError(err)
+		h.writeError(w, r, err)
+		return
 	}
 
-	pageParams := views.CreatePageParams(skip, limit, amount, jokes)
+	if filter.After, err = getCursorParam(r, filter.Sort); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
 
-	err = h.template.Template.ExecuteTemplate(w, views.GetJokesTemplate, pageParams)
+	jokes, amount, err := h.storage.GetJokes(oneOffSeed(ctx, r, filter.Sort), skip, limit, filter)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.writeError(w, r, fmt.Errorf("getting jokes error: %w", err))
+		return
 	}
+
+	pageParams := views.CreatePageParams(skip, limit, amount, jokes)
+	pageParams.Cursor = nextCursor(jokes, limit, filter.Sort)
+	pageParams.Filters = filterQuery(r, filter)
+	pageParams.RandSeed = randSeedOf(filter)
+
+	h.template.Render(w, r, http.StatusOK, views.GetJokesTemplate, pageParams)
 }
 
 func (h Handler) addJoke(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 	defer cancel()
 
-	title := r.FormValue("title")
-	body := r.FormValue("body")
+	input, err := parseJokeRequest(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if input.Title == nil || *input.Title == "" {
+		h.writeError(w, r, newRequestError(codeInvalidJoke, "title is not specified"))
+		return
+	}
 
-	_, err := h.storage.AddJoke(ctx, title, body, 0)
+	joke, err := h.storage.AddJoke(ctx, *input.Title, stringValue(input.Body), 0)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.writeError(w, r, fmt.Errorf("adding joke error: %w", err))
+		return
+	}
 
-		_, err := w.Write([]byte(err.Error()))
-		logResponseWriteError(err)
+	h.suggest.PutJoke(joke)
 
+	if format, _ := views.NegotiateFormat(r); format == views.FormatHTML {
+		http.Redirect(w, r, "/jokes", http.StatusFound)
 		return
 	}
 
-	http.Redirect(w, r, "/jokes", http.StatusFound)
+	w.Header().Set("Location", "/api/v1/jokes/"+joke.ID)
+	h.template.Render(w, r, http.StatusCreated, views.GetJokeByIDTemplate, joke)
 }
 
 func (h Handler) getJokesByText(w http.ResponseWriter, r *http.Request) {
@@ -69,38 +107,81 @@ func (h Handler) getJokesByText(w http.ResponseWriter, r *http.Request) {
 
 	skip, limit, err := getPaginationParams(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		h.writeError(w, r, err)
+		return
 	}
 
 	if text == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		h.writeError(w, r, newRequestError(codeMissingParameter, "text is not specified"))
 		return
 	}
 
-	result, amount, err := h.storage.GetJokesByText(ctx, skip, limit, text)
-	if errors.Is(err, storage.ErrJokeNotFound) {
-		w.WriteHeader(http.StatusNotFound)
+	mode, err := storage.ParseSearchMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
 
+	filter, err := getFilterParams(r)
+	if err != nil {
+		h.writeError(w, r, err)
 		return
-	} else if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	}
 
-		_, err := w.Write([]byte(err.Error()))
-		logResponseWriteError(err)
+	// the pattern is validated before it reaches the storage and highlights the matches.
+	var pattern *regexp.Regexp
+	if mode == storage.SearchRegex {
+		if pattern, err = storage.CompilePattern(text); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+	}
 
+	result, amount, err := h.storage.GetJokesByText(oneOffSeed(ctx, r, filter.Sort), skip, limit, text, mode, filter)
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("searching jokes error: %w", err))
 		return
 	}
 
+	// patterns are no words, which could complete a search request.
+	if amount > 0 && mode != storage.SearchRegex {
+		h.suggest.AddQuery(text)
+	}
+
 	pageParams := views.CreatePageParams(skip, limit, amount, result)
+	pageParams.Filters = filterQuery(r, filter)
+	pageParams.RandSeed = randSeedOf(filter)
+
+	highlights := views.Highlights(text, pageParams.Content)
+	if pattern != nil {
+		highlights = views.PatternHighlights(pattern, pageParams.Content)
+	}
 
-	err = h.template.Template.ExecuteTemplate(w, views.GetJokesByTextTemplate,
+	h.template.Render(w, r, http.StatusOK, views.GetJokesByTextTemplate,
 		views.SearchPageParams{
 			SearchRequest: text,
+			Mode:          mode,
 			PageParams:    pageParams,
+			Highlights:    highlights,
+			Suggestions:   h.suggestions(ctx, text, mode, filter, amount),
 		})
+}
+
+// suggestions returns "did you mean" corrections of the text, when a word search found nothing.
+// They are made of the words of the fuzzy search results, so they always lead to some jokes.
+// Suggestions are optional, a failing fuzzy search is logged and leaves them out.
+func (h Handler) suggestions(ctx context.Context, text string, mode storage.SearchMode, filter storage.Filter, amount int) []string {
+	if amount > 0 || (mode != storage.SearchPlain && mode != storage.SearchText) {
+		return nil
+	}
+
+	similar, _, err := h.storage.GetJokesByText(ctx, 0, suggestionSample, text, storage.SearchFuzzy, filter)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("fuzzy search for suggestions error: %v", err)
+		return nil
 	}
+
+	return storage.Suggestions(text, similar)
 }
 
 func (h Handler) getJokeByID(w http.ResponseWriter, r *http.Request) {
@@ -112,85 +193,353 @@ func (h Handler) getJokeByID(w http.ResponseWriter, r *http.Request) {
 	// id := r.URL.Query().Get("id")
 
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		h.writeError(w, r, newRequestError(codeMissingParameter, "id is not specified"))
 		return
 	}
 
-	result, err := h.cache.Get(id)
+	result, err := h.cache.GetOrLoad(id, func() (models.Joke, error) {
+		return h.storage.GetJokeByID(ctx, id)
+	})
 	if err != nil {
-		fmt.Printf("cache error: %s", err)
+		h.writeError(w, r, fmt.Errorf("getting joke error: %w", err))
+		return
+	}
 
-		result, err = h.storage.GetJokeByID(ctx, id)
-		if errors.Is(err, storage.ErrJokeNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+	h.template.Render(w, r, http.StatusOK, views.GetJokeByIDTemplate, result)
+}
 
-			_, err := w.Write([]byte(err.Error()))
-			logResponseWriteError(err)
+func (h Handler) getRandomJokes(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
 
-			return
-		}
+	skip, limit, err := getPaginationParams(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
 
-		h.cache.Set(id, result, 0)
+	randSeed, err := getRandSeedParam(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
 	}
 
-	err = h.template.Template.ExecuteTemplate(w, views.GetJokeByIDTemplate, result)
+	random, amount, err := h.storage.GetRandomJokes(oneOffSeed(ctx, r, storage.SortRandom), skip, limit, randSeed)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.writeError(w, r, fmt.Errorf("getting random jokes error: %w", err))
+		return
 	}
+
+	pageParams := views.CreatePageParams(skip, limit, amount, random)
+	pageParams.Filters = template.URL("rand_seed=" + strconv.FormatInt(randSeed, 10))
+	pageParams.RandSeed = &randSeed
+
+	h.template.Render(w, r, http.StatusOK, views.GetRandomJokesTemplate, pageParams)
 }
 
-func (h Handler) getRandomJokes(w http.ResponseWriter, r *http.Request) {
+func (h Handler) getFunniestJokes(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 	defer cancel()
 
 	skip, limit, err := getPaginationParams(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		h.writeError(w, r, err)
+		return
 	}
 
-	random, amount, err := h.storage.GetRandomJokes(ctx, limit)
+	filter, err := getFilterParams(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.writeError(w, r, err)
+		return
+	}
 
-		_, err := w.Write([]byte(err.Error()))
-		logResponseWriteError(err)
+	if filter.After, err = getCursorParam(r, filter.Sort); err != nil {
+		h.writeError(w, r, err)
+		return
 	}
 
-	pageParams := views.CreatePageParams(skip, limit, amount, random)
+	funniest, amount, err := h.storage.GetFunniestJokes(oneOffSeed(ctx, r, filter.Sort), skip, limit, filter)
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("getting funniest jokes error: %w", err))
+		return
+	}
+
+	pageParams := views.CreatePageParams(skip, limit, amount, funniest)
+	pageParams.Cursor = nextCursor(funniest, limit, filter.Sort)
+	pageParams.Filters = filterQuery(r, filter)
+	pageParams.RandSeed = randSeedOf(filter)
+
+	h.template.Render(w, r, http.StatusOK, views.GetFunniestJokesTemplate, pageParams)
+}
 
-	err = h.template.Template.ExecuteTemplate(w, views.GetRandomJokesTemplate, pageParams)
+func (h Handler) updateJoke(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+
+	input, err := parseJokeRequest(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.writeError(w, r, err)
+		return
 	}
+
+	if input.Title == nil || *input.Title == "" {
+		h.writeError(w, r, newRequestError(codeInvalidJoke, "title is not specified"))
+		return
+	}
+
+	h.saveJoke(ctx, w, r, id, *input.Title, stringValue(input.Body))
 }
 
-func (h Handler) getFunniestJokes(w http.ResponseWriter, r *http.Request) {
+func (h Handler) patchJoke(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 	defer cancel()
 
-	skip, limit, err := getPaginationParams(r)
+	id := mux.Vars(r)["id"]
+
+	input, err := parseJokeRequest(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		h.writeError(w, r, err)
+		return
 	}
 
-	funniest, amount, err := h.storage.GetFunniestJokes(ctx, skip, limit)
+	joke, err := h.storage.GetJokeByID(ctx, id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.writeError(w, r, fmt.Errorf("getting joke error: %w", err))
+		return
+	}
 
-		_, err := w.Write([]byte(err.Error()))
-		logResponseWriteError(err)
+	if input.Title != nil {
+		joke.Title = *input.Title
 	}
 
-	pageParams := views.CreatePageParams(skip, limit, amount, funniest)
+	if input.Body != nil {
+		joke.Body = *input.Body
+	}
+
+	if joke.Title == "" {
+		h.writeError(w, r, newRequestError(codeInvalidJoke, "title is not specified"))
+		return
+	}
+
+	h.saveJoke(ctx, w, r, id, joke.Title, joke.Body)
+}
 
-	err = h.template.Template.ExecuteTemplate(w, views.GetFunniestJokesTemplate, pageParams)
+func (h Handler) saveJoke(ctx context.Context, w http.ResponseWriter, r *http.Request, id, title, body string) {
+	joke, err := h.storage.UpdateJoke(ctx, id, title, body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.writeError(w, r, fmt.Errorf("updating joke error: %w", err))
+		return
 	}
+
+	h.cache.Delete(id)
+	h.suggest.PutJoke(joke)
+
+	if format, _ := views.NegotiateFormat(r); format == views.FormatHTML {
+		http.Redirect(w, r, "/jokes/"+id, http.StatusSeeOther)
+		return
+	}
+
+	h.template.Render(w, r, http.StatusOK, views.GetJokeByIDTemplate, joke)
+}
+
+func (h Handler) deleteJoke(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+
+	err := h.storage.DeleteJoke(ctx, id)
+	if err != nil {
+		h.writeError(w, r, fmt.Errorf("deleting joke error: %w", err))
+		return
+	}
+
+	h.cache.Delete(id)
+	h.votes.forget(id)
+	h.suggest.RemoveJoke(id)
+
+	if format, _ := views.NegotiateFormat(r); format == views.FormatHTML {
+		http.Redirect(w, r, "/jokes", http.StatusSeeOther)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// filterParams are the query parameters of the list filters and the sort.
+var filterParams = []string{"min_score", "max_score", "field", "min_body_length", "max_body_length", "has_body", "sort"}
+
+// getFilterParams returns the filter given by the query parameters, absent parameters do not filter.
+func getFilterParams(r *http.Request) (storage.Filter, error) {
+	var filter storage.Filter
+
+	query := r.URL.Query()
+
+	for name, value := range map[string]**int{
+		"min_score":       &filter.MinScore,
+		"max_score":       &filter.MaxScore,
+		"min_body_length": &filter.MinBodyLength,
+		"max_body_length": &filter.MaxBodyLength,
+	} {
+		s := query.Get(name)
+		if s == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return storage.Filter{}, newRequestError(codeInvalidFilter, name+" is not a valid number")
+		}
+		*value = &n
+	}
+
+	if s := query.Get("has_body"); s != "" {
+		hasBody, err := strconv.ParseBool(s)
+		if err != nil {
+			return storage.Filter{}, newRequestError(codeInvalidFilter, "has_body is not a valid boolean")
+		}
+		filter.HasBody = &hasBody
+	}
+
+	field, err := storage.ParseField(query.Get("field"))
+	if err != nil {
+		return storage.Filter{}, newRequestError(codeInvalidFilter, "field has to be title or body")
+	}
+	filter.Field = field
+
+	if filter.Sort, err = storage.ParseSort(query.Get("sort")); err != nil {
+		return storage.Filter{}, newRequestError(codeInvalidSort, "sort has to be created, score, -score, title, random or relevance")
+	}
+
+	if filter.Sort == storage.SortRandom {
+		if filter.RandSeed, err = getRandSeedParam(r); err != nil {
+			return storage.Filter{}, err
+		}
+	}
+
+	if err := filter.Validate(); err != nil {
+		return storage.Filter{}, newRequestError(codeInvalidFilter, err.Error())
+	}
+
+	return filter, nil
+}
+
+// getRandSeedParam returns the seed of the random order given by the rand_seed query parameter.
+// A request without a seed gets a new one, which the page links keep.
+func getRandSeedParam(r *http.Request) (int64, error) {
+	s := r.URL.Query().Get("rand_seed")
+	if s == "" {
+		return rand.Int63(), nil
+	}
+
+	seed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, newRequestError(codeInvalidSort, "rand_seed is not a valid number")
+	}
+
+	return seed, nil
+}
+
+// oneOffSeed keeps the pages of a random order out of the page cache, when its seed was generated
+// for the request: they are only asked for again by clients passing the returned seed on.
+func oneOffSeed(ctx context.Context, r *http.Request, sort storage.Sort) context.Context {
+	if sort != storage.SortRandom || r.URL.Query().Get("rand_seed") != "" {
+		return ctx
+	}
+
+	return cached.WithoutCache(ctx)
+}
+
+// getCursorParam returns the cursor given by the cursor query parameter or nil, when it is absent.
+func getCursorParam(r *http.Request, sort storage.Sort) (*storage.Cursor, error) {
+	token := r.URL.Query().Get("cursor")
+	if token == "" {
+		return nil, nil
+	}
+
+	if sort != storage.SortDefault && !sort.SupportsCursor() {
+		return nil, newRequestError(codeInvalidCursor, "the "+string(sort)+" order does not support cursors")
+	}
+
+	cursor, err := storage.ParseCursor(token)
+	if err != nil {
+		return nil, newRequestError(codeInvalidCursor, "cursor is not valid")
+	}
+
+	return &cursor, nil
+}
+
+// nextCursor returns the token continuing the listing after the page or an empty string,
+// when the page is not full, so there are no more jokes, or the order does not support cursors.
+func nextCursor(page []models.Joke, limit int, sort storage.Sort) string {
+	if limit == 0 || len(page) < limit || (sort != storage.SortDefault && !sort.SupportsCursor()) {
+		return ""
+	}
+
+	return storage.CursorAfter(page[len(page)-1]).String()
+}
+
+// randSeedOf returns the seed of the random order of the filter or nil for other orders.
+func randSeedOf(filter storage.Filter) *int64 {
+	if filter.Sort != storage.SortRandom {
+		return nil
+	}
+
+	return &filter.RandSeed
+}
+
+// filterQuery returns the filter parameters of the request, so the pagination links keep the filter and its order.
+func filterQuery(r *http.Request, filter storage.Filter) template.URL {
+	query := url.Values{}
+
+	for _, name := range filterParams {
+		if value := r.URL.Query().Get(name); value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	if filter.Sort == storage.SortRandom {
+		query.Set("rand_seed", strconv.FormatInt(filter.RandSeed, 10))
+	}
+
+	return template.URL(query.Encode())
+}
+
+func parseJokeRequest(r *http.Request) (jokeRequest, error) {
+	var input jokeRequest
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return jokeRequest{}, newRequestError(codeInvalidJoke, "request body is not valid JSON: "+err.Error())
+		}
+
+		return input, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return jokeRequest{}, newRequestError(codeInvalidJoke, "request form is not valid: "+err.Error())
+	}
+
+	if values, ok := r.Form["title"]; ok {
+		input.Title = &values[0]
+	}
+
+	if values, ok := r.Form["body"]; ok {
+		input.Body = &values[0]
+	}
+
+	return input, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
 }
 
 func getPaginationParams(r *http.Request) (int, int, error) {
@@ -206,10 +555,10 @@ func getPaginationParams(r *http.Request) (int, int, error) {
 	} else {
 		skip, err = strconv.Atoi(skipStr)
 		if err != nil {
-			return 0, 0, fmt.Errorf("skip is not valid: %w", err)
+			return 0, 0, newRequestError(codeInvalidPagination, "skip is not a valid number")
 		}
 		if skip < 0 {
-			return 0, 0, fmt.Errorf("skip is negative: %w", err)
+			return 0, 0, newRequestError(codeInvalidPagination, "skip is negative")
 		}
 	}
 
@@ -221,17 +570,11 @@ func getPaginationParams(r *http.Request) (int, int, error) {
 	} else {
 		limit, err = strconv.Atoi(limitStr)
 		if err != nil {
-			return 0, 0, fmt.Errorf("seed is not valid: %w", err)
+			return 0, 0, newRequestError(codeInvalidPagination, "seed is not a valid number")
 		}
 		if limit < 0 {
-			return 0, 0, fmt.Errorf("seed is negative: %w", err)
+			return 0, 0, newRequestError(codeInvalidPagination, "seed is negative")
 		}
 	}
 	return skip, limit, nil
 }
-
-func logResponseWriteError(err error) {
-	if err != nil {
-		log.Printf("response writing error: %s", err)
-	}
-}
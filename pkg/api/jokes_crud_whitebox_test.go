@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	file_storage "github.com/DanilLagunov/jokes-api/pkg/storage/file-storage"
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTempFileStorage copies the test jokes into a temporary file, so tests can modify them.
+func newTempFileStorage(t *testing.T) *file_storage.FileStorage {
+	data, err := os.ReadFile("./test-data/test_jokes.json")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jokes.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	storage, err := file_storage.NewFileStorage(path)
+	require.NoError(t, err)
+
+	return storage
+}
+
+func TestAPIAddJoke(t *testing.T) {
+	storage := newTempFileStorage(t)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	for _, body := range []string{`{"body": "No title"}`, `{"title": "", "body": "Empty title"}`} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/jokes", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		h.ServeHTTP(recorder, req)
+		assert.EqualValues(t, http.StatusBadRequest, recorder.Code, body)
+		assert.Contains(t, recorder.Body.String(), codeInvalidJoke, body)
+	}
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jokes", strings.NewReader(`{"title": "New joke", "body": "Body"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusCreated, recorder.Code)
+
+	var joke models.Joke
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &joke))
+	assert.EqualValues(t, "/api/v1/jokes/"+joke.ID, recorder.Header().Get("Location"))
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/"+joke.ID, nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code, "the location points to the created joke")
+}
+
+func TestUpdateJoke(t *testing.T) {
+	storage := newTempFileStorage(t)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	// warm up the cache to make sure the update invalidates it
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/1a7xnd", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	tests := []struct {
+		Method   string
+		Body     string
+		Code     int
+		Expected models.Joke
+	}{
+		{
+			Method:   http.MethodPut,
+			Body:     `{"title": "New title", "body": "New body"}`,
+			Code:     http.StatusOK,
+			Expected: models.Joke{ID: "1a7xnd", Title: "New title", Body: "New body", Score: 44},
+		},
+		{
+			Method:   http.MethodPatch,
+			Body:     `{"body": "Patched body"}`,
+			Code:     http.StatusOK,
+			Expected: models.Joke{ID: "1a7xnd", Title: "New title", Body: "Patched body", Score: 44},
+		},
+		{
+			Method: http.MethodPut,
+			Body:   `{"body": "No title"}`,
+			Code:   http.StatusBadRequest,
+		},
+		{
+			Method: http.MethodPatch,
+			Body:   `{"title": ""}`,
+			Code:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.Method, "/api/v1/jokes/1a7xnd", strings.NewReader(tc.Body))
+		req.Header.Set("Content-Type", "application/json")
+
+		h.ServeHTTP(recorder, req)
+		require.EqualValues(t, tc.Code, recorder.Code, tc.Method+" "+tc.Body)
+
+		if tc.Code != http.StatusOK {
+			continue
+		}
+
+		var joke models.Joke
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &joke))
+		assert.EqualValues(t, tc.Expected, joke)
+
+		recorder = httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/1a7xnd", nil))
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &joke))
+		assert.EqualValues(t, tc.Expected, joke, "cached joke is stale")
+	}
+
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/jokes/unknown", strings.NewReader(`{"title": "Title"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	h.ServeHTTP(recorder, req)
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestEditJokeForm(t *testing.T) {
+	storage := newTempFileStorage(t)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	form := url.Values{}
+	form.Set("title", "Edited title")
+	form.Set("body", "Edited body")
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/jokes/1a7xnd/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	h.ServeHTTP(recorder, req)
+	assert.EqualValues(t, http.StatusSeeOther, recorder.Code)
+	assert.EqualValues(t, "/jokes/1a7xnd", recorder.Header().Get("Location"))
+
+	joke, err := storage.GetJokeByID(req.Context(), "1a7xnd")
+	require.NoError(t, err)
+	assert.EqualValues(t, "Edited title", joke.Title)
+	assert.EqualValues(t, "Edited body", joke.Body)
+}
+
+func TestDeleteJoke(t *testing.T) {
+	storage := newTempFileStorage(t)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/1a7xnd", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/api/v1/jokes/1a7xnd", nil))
+	assert.EqualValues(t, http.StatusNoContent, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/1a7xnd", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jokes/5tz52q/delete", nil))
+	assert.EqualValues(t, http.StatusSeeOther, recorder.Code)
+	assert.EqualValues(t, "/jokes", recorder.Header().Get("Location"))
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/api/v1/jokes/5tz52q", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,533 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	file_storage "github.com/DanilLagunov/jokes-api/pkg/storage/file-storage"
+	"github.com/DanilLagunov/jokes-api/pkg/suggest"
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIGetJokes(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jokes?skip=1&seed=1", nil)
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+
+	var page views.JokesPageParams
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+
+	assert.EqualValues(t, 1, page.Skip)
+	assert.EqualValues(t, 1, page.Seed)
+	assert.EqualValues(t, 2, page.CurrPage)
+	assert.EqualValues(t, 3, page.MaxPage)
+	assert.EqualValues(t, 2, page.Next)
+	assert.EqualValues(t, 0, page.Prev)
+	require.Len(t, page.Content, 1)
+	assert.EqualValues(t, "1a7xnd", page.Content[0].ID)
+}
+
+func TestAPIGetJokeByID(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	tests := []struct {
+		ID       string
+		Code     int
+		Expected models.Joke
+	}{
+		{
+			ID:   "1a7xnd",
+			Code: http.StatusOK,
+			Expected: models.Joke{
+				ID:    "1a7xnd",
+				Title: "What's the difference between a hippie chick and a hockey player?",
+				Body:  "A hockey player showers after three periods.",
+				Score: 44,
+			},
+		},
+		{
+			ID:   "unknown",
+			Code: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/jokes/"+tc.ID, nil)
+
+		h.ServeHTTP(recorder, req)
+		assert.EqualValues(t, tc.Code, recorder.Code)
+
+		if tc.Code != http.StatusOK {
+			continue
+		}
+
+		var joke models.Joke
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &joke))
+		assert.EqualValues(t, tc.Expected, joke)
+	}
+}
+
+func TestAPIGetJokesByText(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search?text=hockey", nil)
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var result views.SearchPageParams
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+
+	assert.EqualValues(t, "hockey", result.SearchRequest)
+	require.NotEmpty(t, result.PageParams.Content)
+	assert.EqualValues(t, "1a7xnd", result.PageParams.Content[0].ID)
+
+	require.Contains(t, result.Highlights, "1a7xnd")
+	assert.Contains(t, result.Highlights["1a7xnd"].Title, views.Fragment{Text: "hockey", Match: true})
+}
+
+func TestAPIGetJokesByTextNoHits(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search?text=penguin", nil)
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var result views.SearchPageParams
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+
+	assert.NotNil(t, result.PageParams.Content)
+	assert.Empty(t, result.PageParams.Content)
+}
+
+func TestAPIGetJokesByTextRegex(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search?text=h.ckey&mode=regex", nil)
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var result views.SearchPageParams
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+
+	assert.EqualValues(t, "regex", result.Mode)
+	require.NotEmpty(t, result.PageParams.Content)
+	assert.EqualValues(t, "1a7xnd", result.PageParams.Content[0].ID)
+
+	require.Contains(t, result.Highlights, "1a7xnd")
+	assert.Contains(t, result.Highlights["1a7xnd"].Title, views.Fragment{Text: "hockey", Match: true})
+}
+
+func TestAPIGetJokesCursor(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	for _, tt := range []struct {
+		path string
+		want [][]string
+	}{
+		{"/api/v1/jokes", [][]string{{"5tz52q", "1a7xnd"}, {"5tz319"}}},
+		{"/api/v1/jokes/funniest", [][]string{{"1a7xnd", "5tz52q"}, {"5tz319"}}},
+	} {
+		url := tt.path + "?seed=2"
+
+		for i, want := range tt.want {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+			require.EqualValues(t, http.StatusOK, recorder.Code, url)
+
+			var page views.JokesPageParams
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+
+			ids := []string{}
+			for _, joke := range page.Content {
+				ids = append(ids, joke.ID)
+			}
+			assert.Equal(t, want, ids, url)
+			assert.EqualValues(t, 2, page.MaxPage, "the amount counts the whole listing")
+
+			if i == len(tt.want)-1 {
+				assert.Empty(t, page.Cursor, "the last page has no cursor")
+				break
+			}
+
+			require.NotEmpty(t, page.Cursor, url)
+			url = tt.path + "?seed=2&cursor=" + page.Cursor
+		}
+	}
+}
+
+func TestAPIGetJokesSorted(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"/api/v1/jokes?sort=-score", []string{"1a7xnd", "5tz52q", "5tz319"}},
+		{"/api/v1/jokes?sort=title", []string{"5tz52q", "5tz319", "1a7xnd"}},
+		{"/api/v1/jokes/funniest?sort=score", []string{"5tz319", "5tz52q", "1a7xnd"}},
+		{"/api/v1/jokes/funniest?sort=created", []string{"5tz52q", "1a7xnd", "5tz319"}},
+	}
+
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		require.EqualValues(t, http.StatusOK, recorder.Code, tt.url)
+
+		var page views.JokesPageParams
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+
+		ids := []string{}
+		for _, joke := range page.Content {
+			ids = append(ids, joke.ID)
+		}
+		assert.Equal(t, tt.want, ids, tt.url)
+	}
+
+	random := func(url string) []models.Joke {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		require.EqualValues(t, http.StatusOK, recorder.Code, url)
+
+		var page views.JokesPageParams
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+		assert.Empty(t, page.Cursor, "the random order does not support cursors")
+
+		return page.Content
+	}
+	assert.Equal(t, random("/api/v1/jokes?sort=random&rand_seed=7"), random("/api/v1/jokes/funniest?sort=random&rand_seed=7"))
+}
+
+func TestAPIGetRandomJokesSeeded(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	random := func(url string) []models.Joke {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		require.EqualValues(t, http.StatusOK, recorder.Code, url)
+
+		var page views.JokesPageParams
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+
+		return page.Content
+	}
+
+	all := random("/api/v1/jokes/random?seed=3&rand_seed=7")
+	require.Len(t, all, 3)
+	assert.Equal(t, all, random("/api/v1/jokes/random?seed=3&rand_seed=7"), "the same seed gives the same jokes")
+	assert.Equal(t, all, random("/api/v1/jokes?sort=random&rand_seed=7"))
+
+	paged := append(random("/api/v1/jokes/random?seed=2&rand_seed=7"), random("/api/v1/jokes/random?skip=2&seed=2&rand_seed=7")...)
+	assert.Equal(t, all, paged, "pages of the same seed make up one permutation")
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/jokes/random?seed=1", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	assert.Regexp(t, `href="/jokes/random\?skip=1&seed=1&rand_seed=\d+"`, recorder.Body.String(),
+		"a new seed is kept by the links")
+
+	page := func(url string) views.JokesPageParams {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		require.EqualValues(t, http.StatusOK, recorder.Code, url)
+
+		var page views.JokesPageParams
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+
+		return page
+	}
+
+	first := page("/api/v1/jokes/random?seed=2")
+	require.NotNil(t, first.RandSeed, "a new seed is returned")
+	next := page("/api/v1/jokes/random?skip=2&seed=2&rand_seed=" + strconv.FormatInt(*first.RandSeed, 10))
+	assert.ElementsMatch(t, all, append(first.Content, next.Content...), "the returned seed gives the other pages")
+
+	assert.EqualValues(t, 7, *page("/api/v1/jokes?sort=random&rand_seed=7").RandSeed)
+	assert.Nil(t, page("/api/v1/jokes").RandSeed, "other orders have no seed")
+}
+
+func TestAPIGetJokesFiltered(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"/api/v1/jokes?min_score=1", []string{"5tz52q", "1a7xnd"}},
+		{"/api/v1/jokes?max_score=0&has_body=true", []string{"5tz319"}},
+		{"/api/v1/jokes?min_body_length=50&max_body_length=100", []string{"5tz52q"}},
+		{"/api/v1/jokes/funniest?max_score=1", []string{"5tz52q", "5tz319"}},
+		{"/api/v1/jokes/funniest?has_body=false", []string{}},
+	}
+
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		require.EqualValues(t, http.StatusOK, recorder.Code, tt.url)
+
+		var page views.JokesPageParams
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+
+		ids := []string{}
+		for _, joke := range page.Content {
+			ids = append(ids, joke.ID)
+		}
+		assert.Equal(t, tt.want, ids, tt.url)
+	}
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search?text=hippie&field=body", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var result views.SearchPageParams
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Empty(t, result.PageParams.Content, "hippie is in the title only")
+}
+
+func TestFilterLinks(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/jokes?seed=1&min_score=0&unknown=1", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	assert.Contains(t, recorder.Body.String(), `href="/jokes?skip=1&seed=1&min_score=0"`)
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/jokes/funniest?seed=1&sort=random", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	assert.Regexp(t, `href="/jokes/funniest\?skip=1&seed=1&rand_seed=\d+&amp;sort=random"`, recorder.Body.String(),
+		"a new seed is kept by the links")
+}
+
+func TestAPIGetJokesByTextSuggestions(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search?text=hokey", nil)
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var result views.SearchPageParams
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+
+	assert.Empty(t, result.PageParams.Content)
+	assert.Contains(t, result.Suggestions, "hockey")
+
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search?text=hokey&mode=fuzzy", nil)
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	result = views.SearchPageParams{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+
+	require.NotEmpty(t, result.PageParams.Content)
+	assert.EqualValues(t, "1a7xnd", result.PageParams.Content[0].ID)
+	assert.Empty(t, result.Suggestions, "jokes were found")
+
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/jokes/search/?text=hokey", nil)
+
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `Did you mean <a href="/jokes/search/?text=hockey&mode=plain">hockey</a>?`)
+}
+
+func TestAPIGetPaginationError(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jokes/funniest?skip=-1", nil)
+
+	h.ServeHTTP(recorder, req)
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestGetJokesContentNegotiation(t *testing.T) {
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	tests := []struct {
+		URL         string
+		Accept      string
+		ContentType string
+	}{
+		{"/jokes", "text/html", "text/html; charset=utf-8"},
+		{"/jokes", "application/json", "application/json; charset=utf-8"},
+		{"/jokes/funniest?format=ndjson", "", "application/x-ndjson; charset=utf-8"},
+		{"/jokes/1a7xnd?format=csv", "", "text/csv; charset=utf-8"},
+		{"/api/v1/jokes?format=csv", "text/html", "application/json; charset=utf-8"},
+	}
+
+	for _, tc := range tests {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.URL, nil)
+		req.Header.Set("Accept", tc.Accept)
+
+		h.ServeHTTP(recorder, req)
+		assert.EqualValues(t, http.StatusOK, recorder.Code, tc.URL)
+		assert.EqualValues(t, tc.ContentType, recorder.Header().Get("Content-Type"), tc.URL)
+	}
+}
+
+func TestAPIGetSuggestions(t *testing.T) {
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(newTempFileStorage(t), template, cache)
+	t.Cleanup(h.Close)
+	<-h.suggest.ready
+
+	suggestions := func(url string) suggest.Suggestions {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		require.EqualValues(t, http.StatusOK, recorder.Code, url)
+		assert.EqualValues(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"), url)
+
+		var result suggest.Suggestions
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		return result
+	}
+
+	result := suggestions("/api/v1/jokes/search/suggest?prefix=i+hate")
+	assert.EqualValues(t, "i hate", result.Prefix)
+	assert.Contains(t, result.Titles, "I hate how you cant even say black paint anymore")
+	assert.Empty(t, result.Terms)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search?text=hockey", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code)
+
+	assert.Equal(t, []string{"hockey"}, suggestions("/jokes/search/suggest?prefix=hoc").Terms, "successful searches are suggested")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jokes", strings.NewReader(`{"title": "Zebra crossing", "body": "Stripes"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	require.EqualValues(t, http.StatusCreated, recorder.Code)
+
+	assert.Equal(t, []string{"Zebra crossing"}, suggestions("/api/v1/jokes/search/suggest?prefix=ZEB").Titles, "new jokes are suggested")
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search/suggest", nil))
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestAPIGetSuggestionsLoading(t *testing.T) {
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(failingStorage{newTempFileStorage(t), errors.New("server selection error")}, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/jokes/search/suggest?prefix=i+hate", nil))
+	require.EqualValues(t, http.StatusOK, recorder.Code, "suggestions do not wait for the titles")
+	assert.Empty(t, recorder.Header().Get("Cache-Control"), "empty suggestions are not reused")
+
+	var result suggest.Suggestions
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Equal(t, suggest.Suggestions{Prefix: "i hate", Titles: []string{}, Terms: []string{}}, result)
+
+	// the failed load waits to be retried, closing the handler ends it.
+	closed := make(chan struct{})
+	go func() {
+		h.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("the load of the titles is not stopped")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
 	file_storage "github.com/DanilLagunov/jokes-api/pkg/storage/file-storage"
 	"github.com/DanilLagunov/jokes-api/pkg/views"
 	"github.com/gorilla/mux"
@@ -22,10 +23,12 @@ import (
 )
 
 func TestGetJokes(t *testing.T) {
-	storage := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
 	template := views.NewTemptale("../../templates/")
-	cache := memcache.NewMemCache(20*time.Second, 1*time.Minute)
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
 	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
 
 	recorder := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/jokes", nil)
@@ -41,10 +44,12 @@ func TestGetJokes(t *testing.T) {
 }
 
 func TestGetFunniestJokes(t *testing.T) {
-	storage := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
 	template := views.NewTemptale("../../templates/")
-	cache := memcache.NewMemCache(20*time.Second, 1*time.Minute)
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
 	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
 
 	recorder := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/jokes/funniest", nil)
@@ -61,10 +66,12 @@ func TestGetFunniestJokes(t *testing.T) {
 }
 
 func TestGetRandomJokes(t *testing.T) {
-	storage := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
 	template := views.NewTemptale("../../templates/")
-	cache := memcache.NewMemCache(20*time.Second, 1*time.Minute)
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
 	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
 
 	recorder := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/jokes/random", nil)
@@ -83,10 +90,12 @@ func TestGetRandomJokes(t *testing.T) {
 }
 
 func TestGetJokeByText(t *testing.T) {
-	storage := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
 	template := views.NewTemptale("../../templates/")
-	cache := memcache.NewMemCache(20*time.Second, 1*time.Minute)
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
 	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
 
 	recorder := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/jokes/search", nil)
@@ -107,10 +116,12 @@ func TestGetJokeByText(t *testing.T) {
 }
 
 func TestGetJokeByID(t *testing.T) {
-	storage := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	storage, err := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	require.NoError(t, err)
 	template := views.NewTemptale("../../templates/")
-	cache := memcache.NewMemCache(20*time.Second, 1*time.Minute)
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
 	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
 
 	recorder := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/jokes/", nil)
@@ -135,17 +146,18 @@ func TestGetJokeByID(t *testing.T) {
 }
 
 func TestAddJoke(t *testing.T) {
-	storage := file_storage.NewFileStorage("./test-data/test_jokes.json")
+	storage := newTempFileStorage(t)
 	template := views.NewTemptale("../../templates/")
-	cache := memcache.NewMemCache(20*time.Second, 1*time.Minute)
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
 	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
 
 	form := url.Values{}
 	form.Set("title", "Test Joke")
 	form.Set("body", "Test joke body")
 
 	recorder := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodGet, "/jokes/add", strings.NewReader(form.Encode()))
+	req := httptest.NewRequest(http.MethodPost, "/jokes/add", strings.NewReader(form.Encode()))
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("Content-Length", strconv.Itoa(len(form.Encode())))
 
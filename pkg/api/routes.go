@@ -3,19 +3,53 @@ package api
 import (
 	"net/http"
 
+	"github.com/DanilLagunov/jokes-api/pkg/views"
 	"github.com/gorilla/mux"
 )
 
 func (h Handler) initRoutes() *mux.Router {
 	h.Router = mux.NewRouter()
+	h.Router.NotFoundHandler = http.HandlerFunc(h.notFound)
+	h.Router.MethodNotAllowedHandler = http.HandlerFunc(h.methodNotAllowed)
 	h.Router.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir("./assets/"))))
 
 	h.Router.HandleFunc("/jokes", h.getJokes).Methods(http.MethodGet)
 	h.Router.HandleFunc("/jokes/add", h.addJoke).Methods(http.MethodPost)
 	h.Router.HandleFunc("/jokes/random", h.getRandomJokes).Methods(http.MethodGet)
 	h.Router.HandleFunc("/jokes/funniest", h.getFunniestJokes).Methods(http.MethodGet)
+	h.Router.Handle("/jokes/search/suggest", forceFormat(views.FormatJSON)(http.HandlerFunc(h.getSuggestions))).Methods(http.MethodGet)
 	h.Router.HandleFunc("/jokes/{id}", h.getJokeByID).Methods(http.MethodGet)
+	h.Router.HandleFunc("/jokes/{id}", h.updateJoke).Methods(http.MethodPut)
+	h.Router.HandleFunc("/jokes/{id}", h.patchJoke).Methods(http.MethodPatch)
+	h.Router.HandleFunc("/jokes/{id}", h.deleteJoke).Methods(http.MethodDelete)
+	h.Router.HandleFunc("/jokes/{id}/edit", h.updateJoke).Methods(http.MethodPost)
+	h.Router.HandleFunc("/jokes/{id}/delete", h.deleteJoke).Methods(http.MethodPost)
+	h.Router.HandleFunc("/jokes/{id}/upvote", h.upvoteJoke).Methods(http.MethodPost)
+	h.Router.HandleFunc("/jokes/{id}/downvote", h.downvoteJoke).Methods(http.MethodPost)
 	h.Router.HandleFunc("/jokes/search/", h.getJokesByText).Methods(http.MethodGet).Queries("text", "{text}")
 
+	v1 := h.Router.PathPrefix("/api/v1").Subrouter()
+	v1.Use(forceFormat(views.FormatJSON))
+	v1.HandleFunc("/jokes", h.getJokes).Methods(http.MethodGet)
+	v1.HandleFunc("/jokes", h.addJoke).Methods(http.MethodPost)
+	v1.HandleFunc("/jokes/random", h.getRandomJokes).Methods(http.MethodGet)
+	v1.HandleFunc("/jokes/funniest", h.getFunniestJokes).Methods(http.MethodGet)
+	v1.HandleFunc("/jokes/search/suggest", h.getSuggestions).Methods(http.MethodGet)
+	v1.HandleFunc("/jokes/search", h.getJokesByText).Methods(http.MethodGet).Queries("text", "{text}")
+	v1.HandleFunc("/jokes/{id}", h.getJokeByID).Methods(http.MethodGet)
+	v1.HandleFunc("/jokes/{id}", h.updateJoke).Methods(http.MethodPut)
+	v1.HandleFunc("/jokes/{id}", h.patchJoke).Methods(http.MethodPatch)
+	v1.HandleFunc("/jokes/{id}", h.deleteJoke).Methods(http.MethodDelete)
+	v1.HandleFunc("/jokes/{id}/upvote", h.upvoteJoke).Methods(http.MethodPost)
+	v1.HandleFunc("/jokes/{id}/downvote", h.downvoteJoke).Methods(http.MethodPost)
+
 	return h.Router
 }
+
+func forceFormat(f views.Format) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(views.WithFormat(r.Context(), f)))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/cached"
+	"github.com/DanilLagunov/jokes-api/pkg/suggest"
+)
+
+// suggestLimit is the number of titles and terms returned for a prefix.
+const suggestLimit = 5
+
+const (
+	// suggestLoadTimeout bounds a single attempt to load the titles of the jokes.
+	suggestLoadTimeout = time.Minute
+	// suggestRetryDelay is the pause after a failed load before the next attempt.
+	suggestRetryDelay = 10 * time.Second
+)
+
+// suggestIndex loads the titles of the jokes from the storage in the background, a failed load is retried
+// after a while. Until the load is done, no suggestions are made, writes keep it up to date before and after it.
+type suggestIndex struct {
+	*suggest.Suggester
+	// ready is closed, when the titles are loaded.
+	ready chan struct{}
+	// stop cancels the load, done is closed, when the load has ended.
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+func newSuggestIndex(s storage.Storage) *suggestIndex {
+	ctx, stop := context.WithCancel(context.Background())
+
+	x := &suggestIndex{
+		Suggester: suggest.NewSuggester(suggestLimit),
+		ready:     make(chan struct{}),
+		stop:      stop,
+		done:      make(chan struct{}),
+	}
+
+	// the batches are read once, caching them would only push the pages of the clients out of the cache.
+	go x.load(cached.WithoutCache(ctx), s)
+
+	return x
+}
+
+func (x *suggestIndex) load(ctx context.Context, s storage.Storage) {
+	defer close(x.done)
+
+	for {
+		loadCtx, cancel := context.WithTimeout(ctx, suggestLoadTimeout)
+		err := x.Load(loadCtx, s)
+		cancel()
+
+		if err == nil {
+			close(x.ready)
+			return
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("%v, retrying in %v", err, suggestRetryDelay)
+
+		retry := time.NewTimer(suggestRetryDelay)
+		select {
+		case <-ctx.Done():
+			retry.Stop()
+			return
+		case <-retry.C:
+		}
+	}
+}
+
+// close stops loading the titles and waits until the load has ended.
+func (x *suggestIndex) close() {
+	x.stop()
+	<-x.done
+}
+
+// suggest returns the suggestions for the prefix, they are empty until the titles are loaded.
+func (x *suggestIndex) suggest(prefix string) (suggest.Suggestions, bool) {
+	select {
+	case <-x.ready:
+		return x.Suggest(prefix, suggestLimit), true
+	default:
+		return suggest.Suggestions{Prefix: prefix, Titles: []string{}, Terms: []string{}}, false
+	}
+}
+
+func (h Handler) getSuggestions(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		h.writeError(w, r, newRequestError(codeMissingParameter, "prefix is not specified"))
+		return
+	}
+
+	suggestions, ready := h.suggest.suggest(prefix)
+
+	// suggestions are requested on every keystroke, browsers may reuse them for a while,
+	// but not the empty ones made while the titles are loaded.
+	if ready {
+		w.Header().Set("Cache-Control", "max-age=60")
+	}
+	h.template.Render(w, r, http.StatusOK, "", suggestions)
+}
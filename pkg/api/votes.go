@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// voteTTL is how long a vote is remembered, a client can vote for the joke again afterwards.
+	voteTTL = 24 * time.Hour
+	// maxVotes bounds the votes remembered, the oldest ones are forgotten first.
+	maxVotes = 100000
+)
+
+// voteRegistry remembers which clients have already voted for which jokes.
+type voteRegistry struct {
+	sync.Mutex
+	votes map[string]map[string]time.Time
+	size  int
+	ttl   time.Duration
+	limit int
+}
+
+func newVoteRegistry() *voteRegistry {
+	return &voteRegistry{votes: make(map[string]map[string]time.Time), ttl: voteTTL, limit: maxVotes}
+}
+
+// register records the vote of the client for the joke and reports false if the client has already voted.
+func (v *voteRegistry) register(jokeID, client string) bool {
+	v.Lock()
+	defer v.Unlock()
+
+	now := time.Now()
+
+	votedAt, voted := v.votes[jokeID][client]
+	if voted && now.Sub(votedAt) < v.ttl {
+		return false
+	}
+
+	if !voted && v.size >= v.limit {
+		v.prune(now)
+	}
+
+	voters, ok := v.votes[jokeID]
+	if !ok {
+		voters = make(map[string]time.Time)
+		v.votes[jokeID] = voters
+	}
+
+	if _, ok := voters[client]; !ok {
+		v.size++
+	}
+	voters[client] = now
+
+	return true
+}
+
+// unregister forgets the vote of the client, so it can vote again after a failed update.
+func (v *voteRegistry) unregister(jokeID, client string) {
+	v.Lock()
+	defer v.Unlock()
+
+	v.remove(jokeID, client)
+}
+
+// forget removes all votes for the joke.
+func (v *voteRegistry) forget(jokeID string) {
+	v.Lock()
+	defer v.Unlock()
+
+	v.size -= len(v.votes[jokeID])
+	delete(v.votes, jokeID)
+}
+
+// prune drops the expired votes and, while the registry stays full, the oldest ones until it is filled
+// to three quarters, so not every new vote has to prune. The caller must hold the lock.
+func (v *voteRegistry) prune(now time.Time) {
+	times := make([]time.Time, 0, v.size)
+
+	for jokeID, voters := range v.votes {
+		for client, votedAt := range voters {
+			if now.Sub(votedAt) >= v.ttl {
+				v.remove(jokeID, client)
+				continue
+			}
+
+			times = append(times, votedAt)
+		}
+	}
+
+	keep := v.limit * 3 / 4
+	if len(times) <= keep {
+		return
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+	oldest := times[keep]
+
+	for jokeID, voters := range v.votes {
+		for client, votedAt := range voters {
+			if !votedAt.After(oldest) {
+				v.remove(jokeID, client)
+			}
+		}
+	}
+}
+
+// remove deletes the vote of the client and the voters of the joke, when it was the last one.
+// The caller must hold the lock.
+func (v *voteRegistry) remove(jokeID, client string) {
+	voters := v.votes[jokeID]
+	if _, ok := voters[client]; !ok {
+		return
+	}
+
+	delete(voters, client)
+	v.size--
+
+	if len(voters) == 0 {
+		delete(v.votes, jokeID)
+	}
+}
+
+func (h Handler) upvoteJoke(w http.ResponseWriter, r *http.Request) {
+	h.vote(w, r, 1)
+}
+
+func (h Handler) downvoteJoke(w http.ResponseWriter, r *http.Request) {
+	h.vote(w, r, -1)
+}
+
+func (h Handler) vote(w http.ResponseWriter, r *http.Request, delta int) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+	client := h.clientAddress(r)
+
+	if !h.votes.register(id, client) {
+		h.writeError(w, r, errAlreadyVoted)
+		return
+	}
+
+	joke, err := h.storage.IncrementScore(ctx, id, delta)
+	if err != nil {
+		h.votes.unregister(id, client)
+		h.writeError(w, r, fmt.Errorf("voting error: %w", err))
+
+		return
+	}
+
+	h.cache.Delete(id)
+
+	if format, _ := views.NegotiateFormat(r); format == views.FormatHTML {
+		http.Redirect(w, r, "/jokes/"+id, http.StatusSeeOther)
+		return
+	}
+
+	h.template.Render(w, r, http.StatusOK, views.GetJokeByIDTemplate, joke)
+}
+
+// ParseTrustedProxies parses the addresses and networks of the proxies, whose X-Real-IP header is trusted.
+// A single address stands for a network of only that address.
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// clientAddress returns the address of the client. The one reported by the X-Real-IP header is only used,
+// when the request comes from a trusted proxy, as any client could send the header otherwise.
+func (h Handler) clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !h.trustedProxy(host) {
+		return host
+	}
+
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+
+	return host
+}
+
+// trustedProxy reports whether the host is one of the trusted proxies.
+func (h Handler) trustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range h.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoteJoke(t *testing.T) {
+	storage := newTempFileStorage(t)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+
+	// httptest sends the requests from 192.0.2.1.
+	proxies, err := ParseTrustedProxies([]string{"192.0.2.1"})
+	require.NoError(t, err)
+	h := NewHandler(storage, template, cache, WithTrustedProxies(proxies))
+	t.Cleanup(h.Close)
+
+	tests := []struct {
+		URL           string
+		Client        string
+		Code          int
+		ExpectedScore int
+	}{
+		{"/api/v1/jokes/1a7xnd/upvote", "10.0.0.1", http.StatusOK, 45},
+		{"/api/v1/jokes/1a7xnd/upvote", "10.0.0.1", http.StatusConflict, 0},
+		{"/api/v1/jokes/1a7xnd/downvote", "10.0.0.1", http.StatusConflict, 0},
+		{"/api/v1/jokes/1a7xnd/upvote", "10.0.0.2", http.StatusOK, 46},
+		{"/api/v1/jokes/1a7xnd/downvote", "10.0.0.3", http.StatusOK, 45},
+		{"/api/v1/jokes/5tz52q/downvote", "10.0.0.1", http.StatusOK, 0},
+		{"/api/v1/jokes/unknown/upvote", "10.0.0.1", http.StatusNotFound, 0},
+		{"/api/v1/jokes/unknown/upvote", "10.0.0.1", http.StatusNotFound, 0},
+	}
+
+	for _, tc := range tests {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tc.URL, nil)
+		req.Header.Set("X-Real-IP", tc.Client)
+
+		h.ServeHTTP(recorder, req)
+		require.EqualValues(t, tc.Code, recorder.Code, tc.URL+" "+tc.Client)
+
+		if tc.Code != http.StatusOK {
+			continue
+		}
+
+		var joke models.Joke
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &joke))
+		assert.EqualValues(t, tc.ExpectedScore, joke.Score)
+	}
+
+	joke, err := storage.GetJokeByID(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "1a7xnd")
+	require.NoError(t, err)
+	assert.EqualValues(t, 45, joke.Score)
+}
+
+func TestVoteJokeForm(t *testing.T) {
+	storage := newTempFileStorage(t)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+	h := NewHandler(storage, template, cache)
+	t.Cleanup(h.Close)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jokes/1a7xnd/upvote", nil))
+
+	assert.EqualValues(t, http.StatusSeeOther, recorder.Code)
+	assert.EqualValues(t, "/jokes/1a7xnd", recorder.Header().Get("Location"))
+}
+
+func TestVoteJokeUntrustedProxy(t *testing.T) {
+	storage := newTempFileStorage(t)
+	template := views.NewTemptale("../../templates/")
+	cache := memcache.NewMemCache[models.Joke](20*time.Second, 1*time.Minute)
+
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	h := NewHandler(storage, template, cache, WithTrustedProxies(proxies))
+	t.Cleanup(h.Close)
+
+	codes := []int{}
+	for _, client := range []string{"10.0.0.1", "10.0.0.2"} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/jokes/1a7xnd/upvote", nil)
+		req.Header.Set("X-Real-IP", client)
+
+		h.ServeHTTP(recorder, req)
+		codes = append(codes, recorder.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusConflict}, codes, "the header of an untrusted client is ignored")
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1", "", "::1"})
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+	assert.Equal(t, "10.0.0.0/8", proxies[0].String())
+	assert.Equal(t, "192.0.2.1/32", proxies[1].String())
+	assert.Equal(t, "::1/128", proxies[2].String())
+
+	_, err = ParseTrustedProxies([]string{"proxy"})
+	assert.Error(t, err)
+}
+
+func TestVoteRegistryExpires(t *testing.T) {
+	votes := newVoteRegistry()
+
+	require.True(t, votes.register("a", "10.0.0.1"))
+	require.False(t, votes.register("a", "10.0.0.1"))
+
+	votes.votes["a"]["10.0.0.1"] = time.Now().Add(-voteTTL)
+	assert.True(t, votes.register("a", "10.0.0.1"), "expired votes are forgotten")
+	assert.Equal(t, 1, votes.size)
+}
+
+func TestVoteRegistryIsBounded(t *testing.T) {
+	votes := newVoteRegistry()
+	votes.limit = 8
+
+	for i := 0; i < 8; i++ {
+		require.True(t, votes.register("a", strconv.Itoa(i)))
+		votes.votes["a"][strconv.Itoa(i)] = time.Now().Add(time.Duration(i-8) * time.Minute)
+	}
+
+	require.True(t, votes.register("b", "8"))
+	assert.Equal(t, 7, votes.size, "the registry is pruned to three quarters before the vote")
+	assert.False(t, votes.register("a", "7"), "recent votes are kept")
+	assert.True(t, votes.register("a", "0"), "the oldest votes are forgotten")
+
+	votes.forget("a")
+	assert.Equal(t, 1, votes.size)
+}
@@ -3,8 +3,6 @@ package cache
 import (
 	"errors"
 	"time"
-
-	"github.com/DanilLagunov/jokes-api/pkg/models"
 )
 
 var (
@@ -14,8 +12,31 @@ var (
 	ErrItemExpired = errors.New("item expired")
 )
 
-// Cache interface.
-type Cache interface {
-	Get(key string) (models.Joke, error)
-	Set(key string, value models.Joke, duration time.Duration)
+// Loader loads the value of a key missing from the cache.
+type Loader[V any] func() (V, error)
+
+// Stats describes the use of a cache since its creation.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	// Expirations counts the items removed after their expiration.
+	Expirations uint64
+	// Evictions counts the items removed to keep the cache within its limits.
+	Evictions uint64
+	Entries   int
+	// Bytes is the approximate memory taken by the items.
+	Bytes int64
+}
+
+// Cache interface. It holds values of the type V, so the same cache engines can hold jokes, pages or totals.
+type Cache[V any] interface {
+	Get(key string) (V, error)
+	Set(key string, value V, duration time.Duration)
+	Delete(key string)
+	// Clear removes all items.
+	Clear()
+	// GetOrLoad returns the cached value of the key or caches the value returned by the loader for the
+	// default expiration. Concurrent misses of the same key share a single call of the loader.
+	GetOrLoad(key string, loader Loader[V]) (V, error)
+	Stats() Stats
 }
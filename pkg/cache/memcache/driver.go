@@ -0,0 +1,17 @@
+package memcache
+
+import (
+	"github.com/DanilLagunov/jokes-api/pkg/cache"
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+func init() {
+	cache.Register("memory", func(cfg config.Config) (cache.Cache[models.Joke], error) {
+		return NewBoundedMemCache[models.Joke](cfg.CacheDefaultExpiration, cfg.CacheCleanupInterval, Limits{
+			MaxEntries: cfg.CacheMaxEntries,
+			MaxBytes:   cfg.CacheMaxBytes,
+			Policy:     Policy(cfg.CacheEviction),
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package memcache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+)
+
+// Policy chooses the item evicted when the cache is over its limits.
+type Policy string
+
+const (
+	// PolicyLRU evicts the least recently used item.
+	PolicyLRU Policy = "lru"
+	// PolicyLFU evicts the least frequently used item, the least recently used one among equally used items.
+	PolicyLFU Policy = "lfu"
+)
+
+// ErrUnknownPolicy describes the error when the eviction policy is not supported.
+var ErrUnknownPolicy = errors.New("unknown eviction policy")
+
+// ParsePolicy returns the policy named s, the empty string selects PolicyLRU.
+func ParsePolicy(s string) (Policy, error) {
+	switch policy := Policy(s); policy {
+	case "":
+		return PolicyLRU, nil
+	case PolicyLRU, PolicyLFU:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownPolicy, s)
+	}
+}
+
+// entry is an item stored with its key and the bookkeeping of the eviction policy.
+type entry[V any] struct {
+	key  string
+	item Item[V]
+	size int64
+	// elem is the element of the entry in its recency list.
+	elem *list.Element
+	// bucket is the element of the frequency bucket holding the entry, only used by lfu.
+	bucket *list.Element
+}
+
+// evictor tracks the use of the entries and picks the victims, all of its methods take O(1).
+type evictor[V any] interface {
+	add(e *entry[V])
+	touch(e *entry[V])
+	remove(e *entry[V])
+	// victim returns the entry to evict or nil, when there are no entries.
+	victim() *entry[V]
+}
+
+func newEvictor[V any](policy Policy) evictor[V] {
+	if policy == PolicyLFU {
+		return &lfu[V]{buckets: list.New()}
+	}
+
+	return &lru[V]{entries: list.New()}
+}
+
+// lru keeps the entries from the most recently used.
+type lru[V any] struct {
+	entries *list.List
+}
+
+func (l *lru[V]) add(e *entry[V]) {
+	e.elem = l.entries.PushFront(e)
+}
+
+func (l *lru[V]) touch(e *entry[V]) {
+	l.entries.MoveToFront(e.elem)
+}
+
+func (l *lru[V]) remove(e *entry[V]) {
+	l.entries.Remove(e.elem)
+}
+
+func (l *lru[V]) victim() *entry[V] {
+	if back := l.entries.Back(); back != nil {
+		return back.Value.(*entry[V])
+	}
+
+	return nil
+}
+
+// bucket holds the entries used the same number of times from the most recently used.
+type bucket struct {
+	uses    int
+	entries *list.List
+}
+
+// lfu keeps non-empty buckets ordered from the least used, so the victim is always in the first one.
+type lfu[V any] struct {
+	buckets *list.List
+}
+
+func (l *lfu[V]) add(e *entry[V]) {
+	front := l.buckets.Front()
+	if front == nil || front.Value.(*bucket).uses != 1 {
+		front = l.buckets.PushFront(&bucket{uses: 1, entries: list.New()})
+	}
+
+	l.insert(e, front)
+}
+
+func (l *lfu[V]) touch(e *entry[V]) {
+	current := e.bucket
+	uses := current.Value.(*bucket).uses + 1
+
+	next := current.Next()
+	if next == nil || next.Value.(*bucket).uses != uses {
+		next = l.buckets.InsertAfter(&bucket{uses: uses, entries: list.New()}, current)
+	}
+
+	l.remove(e)
+	l.insert(e, next)
+}
+
+func (l *lfu[V]) remove(e *entry[V]) {
+	b := e.bucket.Value.(*bucket)
+	b.entries.Remove(e.elem)
+
+	if b.entries.Len() == 0 {
+		l.buckets.Remove(e.bucket)
+	}
+}
+
+func (l *lfu[V]) victim() *entry[V] {
+	if front := l.buckets.Front(); front != nil {
+		return front.Value.(*bucket).entries.Back().Value.(*entry[V])
+	}
+
+	return nil
+}
+
+func (l *lfu[V]) insert(e *entry[V], b *list.Element) {
+	e.bucket = b
+	e.elem = b.Value.(*bucket).entries.PushFront(e)
+}
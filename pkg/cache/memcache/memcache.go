@@ -5,30 +5,61 @@ import (
 	"time"
 
 	"github.com/DanilLagunov/jokes-api/pkg/cache"
-	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"golang.org/x/sync/singleflight"
 )
 
-// MemCache struct.
-type MemCache struct {
+// MemCache is an in-memory Cache of the values of the type V.
+type MemCache[V any] struct {
 	sync.RWMutex
 	defaultExpiration time.Duration
 	cleanupInterval   time.Duration
-	items             map[string]Item
+	limits            Limits
+	items             map[string]*entry[V]
+	evictor           evictor[V]
+	bytes             int64
+	stats             cache.Stats
+	loads             singleflight.Group
+	// generation changes on every Delete and Clear, so loads started before them do not cache stale values.
+	generation uint64
 }
 
 // Item struct.
-type Item struct {
-	Value      models.Joke
+type Item[V any] struct {
+	Value      V
 	Created    time.Time
 	Expiration int64
 }
 
+// Limits bounds the cache, zero values leave it unbounded.
+type Limits struct {
+	// MaxEntries is the maximum number of items.
+	MaxEntries int
+	// MaxBytes is the approximate memory budget of the items, see Size.
+	MaxBytes int64
+	// Policy chooses the evicted items, the zero value selects PolicyLRU.
+	Policy Policy
+}
+
 // NewMemCache creating new Cache object.
-func NewMemCache(defaultExpiration, cleanupInterval time.Duration) *MemCache {
-	items := make(map[string]Item)
+func NewMemCache[V any](defaultExpiration, cleanupInterval time.Duration) *MemCache[V] {
+	cache, _ := NewBoundedMemCache[V](defaultExpiration, cleanupInterval, Limits{})
 
-	cache := MemCache{
-		items:             items,
+	return cache
+}
+
+// NewBoundedMemCache creates a Cache, which evicts items by the policy of the limits, when it is over them.
+func NewBoundedMemCache[V any](defaultExpiration, cleanupInterval time.Duration, limits Limits) (*MemCache[V], error) {
+	policy, err := ParsePolicy(string(limits.Policy))
+	if err != nil {
+		return nil, err
+	}
+
+	limits.Policy = policy
+
+	cache := MemCache[V]{
+		items:             make(map[string]*entry[V]),
+		evictor:           newEvictor[V](policy),
+		limits:            limits,
 		defaultExpiration: defaultExpiration,
 		cleanupInterval:   cleanupInterval,
 	}
@@ -37,32 +68,127 @@ func NewMemCache(defaultExpiration, cleanupInterval time.Duration) *MemCache {
 		go cache.cleaner()
 	}
 
-	return &cache
+	return &cache, nil
 }
 
 // Get return cache item by key.
-func (c *MemCache) Get(key string) (models.Joke, error) {
-	c.RLock()
+// Looking an item up counts as its use for the eviction policy, so Get takes the write lock.
+func (c *MemCache[V]) Get(key string) (V, error) {
+	c.Lock()
 
-	defer c.RUnlock()
+	defer c.Unlock()
 
-	item, found := c.items[key]
+	var zero V
+
+	e, found := c.items[key]
 	if !found {
-		return models.Joke{}, cache.ErrKeyNotFound
+		c.stats.Misses++
+		return zero, cache.ErrKeyNotFound
 	}
 
 	currentTime := time.Now().UnixNano()
-	if item.Expiration > 0 {
-		if currentTime > item.Expiration {
-			return models.Joke{}, cache.ErrItemExpired
+	if e.item.Expiration > 0 {
+		if currentTime > e.item.Expiration {
+			c.remove(e)
+			c.stats.Misses++
+			c.stats.Expirations++
+			return zero, cache.ErrItemExpired
 		}
 	}
 
-	return item.Value, nil
+	c.evictor.touch(e)
+	c.stats.Hits++
+
+	return e.item.Value, nil
 }
 
 // Set puts new item into cache.
-func (c *MemCache) Set(key string, value models.Joke, duration time.Duration) {
+// An item larger than the whole byte budget is not cached.
+func (c *MemCache[V]) Set(key string, value V, duration time.Duration) {
+	c.Lock()
+
+	defer c.Unlock()
+
+	c.set(key, value, duration)
+}
+
+// Delete removes item from cache.
+func (c *MemCache[V]) Delete(key string) {
+	c.Lock()
+
+	defer c.Unlock()
+
+	if e, found := c.items[key]; found {
+		c.remove(e)
+	}
+
+	c.generation++
+	c.loads.Forget(key)
+}
+
+// Clear removes all items from cache.
+func (c *MemCache[V]) Clear() {
+	c.Lock()
+
+	defer c.Unlock()
+
+	c.items = make(map[string]*entry[V])
+	c.evictor = newEvictor[V](c.limits.Policy)
+	c.bytes = 0
+	c.generation++
+}
+
+// GetOrLoad returns cache item by key or puts the item returned by the loader into cache.
+// Concurrent misses of the key wait for the loader called by the first of them.
+func (c *MemCache[V]) GetOrLoad(key string, loader cache.Loader[V]) (V, error) {
+	if value, err := c.Get(key); err == nil {
+		return value, nil
+	}
+
+	value, err, _ := c.loads.Do(key, func() (interface{}, error) {
+		c.RLock()
+		generation := c.generation
+		c.RUnlock()
+
+		value, err := loader()
+		if err != nil {
+			return value, err
+		}
+
+		c.Lock()
+		defer c.Unlock()
+
+		if c.generation == generation {
+			c.set(key, value, 0)
+		}
+
+		return value, nil
+	})
+
+	return value.(V), err
+}
+
+// Stats returns the statistics of the cache.
+func (c *MemCache[V]) Stats() cache.Stats {
+	c.RLock()
+
+	defer c.RUnlock()
+
+	stats := c.stats
+	stats.Entries = len(c.items)
+	stats.Bytes = c.bytes
+
+	return stats
+}
+
+// overLimits reports whether adding an item of the size would put the cache over its limits.
+// The room is made before adding, so a new item is never its own victim.
+func (c *MemCache[V]) overLimits(size int64) bool {
+	return (c.limits.MaxEntries > 0 && len(c.items) >= c.limits.MaxEntries) ||
+		(c.limits.MaxBytes > 0 && c.bytes+size > c.limits.MaxBytes)
+}
+
+func (c *MemCache[V]) set(key string, value V, duration time.Duration) {
 	var expiration int64
 
 	if duration == 0 {
@@ -73,38 +199,60 @@ func (c *MemCache) Set(key string, value models.Joke, duration time.Duration) {
 		expiration = time.Now().Add(duration).UnixNano()
 	}
 
-	c.Lock()
+	size := Size(key, value)
 
-	defer c.Unlock()
+	if e, found := c.items[key]; found {
+		c.remove(e)
+	}
+
+	if c.limits.MaxBytes > 0 && size > c.limits.MaxBytes {
+		return
+	}
+
+	for c.overLimits(size) {
+		c.remove(c.evictor.victim())
+		c.stats.Evictions++
+	}
 
-	c.items[key] = Item{
-		Value:      value,
-		Expiration: expiration,
-		Created:    time.Now(),
+	e := &entry[V]{
+		key: key,
+		item: Item[V]{
+			Value:      value,
+			Expiration: expiration,
+			Created:    time.Now(),
+		},
+		size: size,
 	}
+
+	c.items[key] = e
+	c.bytes += size
+	c.evictor.add(e)
 }
 
-func (c *MemCache) cleaner() {
+func (c *MemCache[V]) remove(e *entry[V]) {
+	c.evictor.remove(e)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
+
+func (c *MemCache[V]) cleaner() {
 	for {
 		<-time.After(c.cleanupInterval)
 
-		if c.items == nil {
-			return
-		}
-
 		c.clearExpiredItems()
 	}
 }
 
-func (c *MemCache) clearExpiredItems() {
+func (c *MemCache[V]) clearExpiredItems() {
 	c.Lock()
 
 	defer c.Unlock()
 
 	currentTime := time.Now().UnixNano()
-	for k, i := range c.items {
-		if currentTime > i.Expiration && i.Expiration > 0 {
-			delete(c.items, k)
+	for _, e := range c.items {
+		if currentTime > e.item.Expiration && e.item.Expiration > 0 {
+			c.remove(e)
+			c.stats.Expirations++
 		}
 	}
 
@@ -1,10 +1,14 @@
 package memcache_test
 
 import (
+	"errors"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/DanilLagunov/jokes-api/pkg/cache"
 	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
 	"github.com/DanilLagunov/jokes-api/pkg/models"
 	"github.com/stretchr/testify/assert"
@@ -58,7 +62,7 @@ func TestCache(t *testing.T) {
 		},
 	}
 	var wg sync.WaitGroup
-	cache := memcache.NewMemCache(2*time.Second, 3*time.Second)
+	cache := memcache.NewMemCache[models.Joke](2*time.Second, 3*time.Second)
 	time.Sleep(2*time.Second + 80*time.Millisecond)
 	for _, tc := range tests {
 		for i := 0; i < 1000; i++ {
@@ -92,3 +96,221 @@ func TestCache(t *testing.T) {
 		wg.Wait()
 	}
 }
+
+func TestDelete(t *testing.T) {
+	cache := memcache.NewMemCache[models.Joke](time.Minute, time.Minute)
+	joke := models.Joke{ID: "1", Title: "First", Body: "first"}
+
+	cache.Set(joke.ID, joke, 0)
+	item, err := cache.Get(joke.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, joke, item)
+
+	cache.Delete(joke.ID)
+	_, err = cache.Get(joke.ID)
+	assert.EqualError(t, err, "key not found")
+
+	cache.Delete("unknown")
+}
+
+func TestLRUEviction(t *testing.T) {
+	c, err := memcache.NewBoundedMemCache[models.Joke](time.Minute, 0, memcache.Limits{MaxEntries: 2})
+	require.NoError(t, err)
+
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	c.Set("2", models.Joke{ID: "2"}, 0)
+	_, err = c.Get("1")
+	require.NoError(t, err)
+
+	c.Set("3", models.Joke{ID: "3"}, 0)
+	_, err = c.Get("2")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound, "the least recently used item is evicted")
+
+	for _, key := range []string{"1", "3"} {
+		_, err = c.Get(key)
+		assert.NoError(t, err, key)
+	}
+
+	c.Set("3", models.Joke{ID: "3", Title: "updated"}, 0)
+	assert.Equal(t, 2, c.Stats().Entries, "updates do not evict")
+	assert.EqualValues(t, 1, c.Stats().Evictions)
+}
+
+func TestLFUEviction(t *testing.T) {
+	c, err := memcache.NewBoundedMemCache[models.Joke](time.Minute, 0, memcache.Limits{MaxEntries: 2, Policy: memcache.PolicyLFU})
+	require.NoError(t, err)
+
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	c.Set("2", models.Joke{ID: "2"}, 0)
+	for i := 0; i < 3; i++ {
+		_, err = c.Get("1")
+		require.NoError(t, err)
+	}
+	_, err = c.Get("2")
+	require.NoError(t, err)
+
+	c.Set("3", models.Joke{ID: "3"}, 0)
+	_, err = c.Get("2")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound, "the least frequently used item is evicted")
+
+	c.Set("4", models.Joke{ID: "4"}, 0)
+	_, err = c.Get("3")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound, "a new item is used less than an old one")
+	_, err = c.Get("1")
+	assert.NoError(t, err)
+
+	c.Delete("1")
+	c.Set("5", models.Joke{ID: "5"}, 0)
+	assert.Equal(t, 2, c.Stats().Entries)
+	assert.EqualValues(t, 2, c.Stats().Evictions)
+}
+
+func TestByteBudget(t *testing.T) {
+	joke := models.Joke{ID: "1", Title: "Title", Body: "Body"}
+	size := memcache.Size("1", joke)
+
+	c, err := memcache.NewBoundedMemCache[models.Joke](time.Minute, 0, memcache.Limits{MaxBytes: 2*size + size/2})
+	require.NoError(t, err)
+
+	for _, key := range []string{"1", "2", "3"} {
+		c.Set(key, models.Joke{ID: key, Title: "Title", Body: "Body"}, 0)
+	}
+	assert.Equal(t, 2, c.Stats().Entries)
+	assert.EqualValues(t, 1, c.Stats().Evictions)
+
+	c.Set("large", models.Joke{ID: "large", Body: strings.Repeat("a", int(3*size))}, 0)
+	_, err = c.Get("large")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound, "items larger than the budget are not cached")
+	assert.Equal(t, 2, c.Stats().Entries, "items larger than the budget do not evict others")
+}
+
+func TestUnknownPolicy(t *testing.T) {
+	_, err := memcache.NewBoundedMemCache[models.Joke](time.Minute, 0, memcache.Limits{Policy: "arc"})
+	assert.ErrorIs(t, err, memcache.ErrUnknownPolicy)
+
+	policy, err := memcache.ParsePolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, memcache.PolicyLRU, policy)
+}
+
+func TestClear(t *testing.T) {
+	c := memcache.NewMemCache[models.Joke](time.Minute, 0)
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	c.Set("2", models.Joke{ID: "2"}, 0)
+
+	c.Clear()
+	_, err := c.Get("1")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+	assert.Equal(t, 0, c.Stats().Entries)
+	assert.EqualValues(t, 0, c.Stats().Bytes)
+
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	_, err = c.Get("1")
+	assert.NoError(t, err, "the cache is usable after clearing it")
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := memcache.NewMemCache[models.Joke](time.Minute, 0)
+	joke := models.Joke{ID: "1", Title: "First"}
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func() (models.Joke, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return joke, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrLoad("1", loader)
+			assert.NoError(t, err)
+			assert.Equal(t, joke, value)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls), "concurrent misses share a load")
+
+	value, err := c.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, joke, value)
+
+	_, err = c.GetOrLoad("2", func() (models.Joke, error) {
+		return models.Joke{}, errors.New("storage failure")
+	})
+	assert.EqualError(t, err, "storage failure")
+	_, err = c.Get("2")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound, "failed loads are not cached")
+}
+
+func TestGetOrLoadDeletedDuringLoad(t *testing.T) {
+	c := memcache.NewMemCache[models.Joke](time.Minute, 0)
+
+	value, err := c.GetOrLoad("1", func() (models.Joke, error) {
+		c.Delete("1")
+		return models.Joke{ID: "1", Title: "stale"}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "stale", value.Title)
+
+	_, err = c.Get("1")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound, "values loaded before a deletion are not cached")
+}
+
+func TestStats(t *testing.T) {
+	c := memcache.NewMemCache[models.Joke](time.Minute, 0)
+	joke := models.Joke{ID: "1", Title: "First"}
+
+	c.Set("1", joke, 0)
+	c.Set("2", joke, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	_, err := c.Get("1")
+	require.NoError(t, err)
+	_, err = c.Get("2")
+	assert.ErrorIs(t, err, cache.ErrItemExpired)
+	_, err = c.Get("3")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+
+	assert.Equal(t, cache.Stats{
+		Hits:        1,
+		Misses:      2,
+		Expirations: 1,
+		Entries:     1,
+		Bytes:       memcache.Size("1", joke),
+	}, c.Stats())
+}
+
+func TestOtherValueTypes(t *testing.T) {
+	pages := memcache.NewMemCache[[]models.Joke](time.Minute, 0)
+	page := []models.Joke{{ID: "1", Title: "First"}, {ID: "2", Title: "Second"}}
+
+	pages.Set("jokes?skip=0", page, 0)
+	value, err := pages.Get("jokes?skip=0")
+	require.NoError(t, err)
+	assert.Equal(t, page, value)
+
+	totals := memcache.NewMemCache[int](time.Minute, 0)
+	total, err := totals.GetOrLoad("jokes", func() (int, error) { return 42, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 42, total)
+
+	_, err = totals.Get("unknown")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+}
+
+func TestSize(t *testing.T) {
+	joke := models.Joke{ID: "1", Title: "Title", Body: "Body"}
+
+	assert.Greater(t, memcache.Size("1", joke), memcache.Size("1", models.Joke{}), "strings are counted")
+	assert.Greater(t, memcache.Size("1", []models.Joke{joke, joke}), memcache.Size("1", []models.Joke{joke}),
+		"slices are counted with their elements")
+	assert.Greater(t, memcache.Size("1", &joke), memcache.Size("1", (*models.Joke)(nil)), "pointers are followed")
+	assert.Greater(t, memcache.Size("longer key", 0), memcache.Size("1", 0))
+}
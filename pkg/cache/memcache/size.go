@@ -0,0 +1,71 @@
+package memcache
+
+import "reflect"
+
+// itemOverhead approximates the memory taken by the bookkeeping of an item.
+const itemOverhead = 128
+
+// Size returns the approximate memory taken by the value cached by the key.
+// It follows strings, slices, maps and pointers of the value, shared memory is counted every time it is reached.
+func Size(key string, value interface{}) int64 {
+	size := int64(itemOverhead + len(key))
+
+	if value != nil {
+		v := reflect.ValueOf(value)
+		size += int64(v.Type().Size()) + indirectSize(v)
+	}
+
+	return size
+}
+
+// indirectSize returns the memory referenced by v, which is not a part of v itself.
+func indirectSize(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i))
+		}
+
+		return size
+	case reflect.Array:
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i))
+		}
+
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += indirectSize(v.Field(i))
+		}
+
+		return size
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		elem := v.Elem()
+
+		return int64(elem.Type().Size()) + indirectSize(elem)
+	case reflect.Map:
+		var size int64
+		iter := v.MapRange()
+		for iter.Next() {
+			k, e := iter.Key(), iter.Value()
+			size += int64(k.Type().Size()+e.Type().Size()) + indirectSize(k) + indirectSize(e)
+		}
+
+		return size
+	default:
+		return 0
+	}
+}
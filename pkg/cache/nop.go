@@ -0,0 +1,32 @@
+package cache
+
+import "time"
+
+// Nop is a Cache that stores nothing, so every lookup falls through to the storage.
+type Nop[V any] struct{}
+
+// Get always returns ErrKeyNotFound.
+func (Nop[V]) Get(key string) (V, error) {
+	var zero V
+
+	return zero, ErrKeyNotFound
+}
+
+// Set does nothing.
+func (Nop[V]) Set(key string, value V, duration time.Duration) {}
+
+// Delete does nothing.
+func (Nop[V]) Delete(key string) {}
+
+// Clear does nothing.
+func (Nop[V]) Clear() {}
+
+// GetOrLoad always calls the loader.
+func (Nop[V]) GetOrLoad(key string, loader Loader[V]) (V, error) {
+	return loader()
+}
+
+// Stats returns zero stats.
+func (Nop[V]) Stats() Stats {
+	return Stats{}
+}
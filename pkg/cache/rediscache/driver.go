@@ -0,0 +1,21 @@
+package rediscache
+
+import (
+	"github.com/DanilLagunov/jokes-api/pkg/cache"
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+func init() {
+	cache.Register("redis", func(cfg config.Config) (cache.Cache[models.Joke], error) {
+		if err := cfg.Require("REDIS_ADDR"); err != nil {
+			return nil, err
+		}
+
+		return NewRedisCache[models.Joke](cfg.RedisAddr, cfg.CacheDefaultExpiration, Options{
+			Password:  cfg.RedisPassword,
+			DB:        cfg.RedisDB,
+			KeyPrefix: cfg.RedisKeyPrefix,
+		})
+	})
+}
@@ -0,0 +1,209 @@
+package rediscache_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process server speaking the part of RESP the cache uses.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func startFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeRedis{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go f.serve()
+
+	return f
+}
+
+func (f *fakeRedis) Addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	authenticated := f.password == ""
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			authenticated = len(args) == 2 && args[1] == f.password
+			if !authenticated {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			io.WriteString(conn, "+OK\r\n")
+		case !authenticated:
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+		default:
+			io.WriteString(conn, f.exec(cmd, args[1:]))
+		}
+	}
+}
+
+func (f *fakeRedis) exec(cmd string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.expire()
+
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.values[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "MGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args))
+		for _, key := range args {
+			if value, ok := f.values[key]; ok {
+				reply += bulk(value)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case "SET":
+		f.values[args[0]] = args[1]
+		delete(f.expires, args[0])
+		if len(args) == 4 && strings.ToUpper(args[2]) == "PX" {
+			ms, err := strconv.Atoi(args[3])
+			if err != nil || ms <= 0 {
+				return "-ERR invalid expire time in 'set' command\r\n"
+			}
+			f.expires[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args {
+			if _, ok := f.values[key]; ok {
+				delete(f.values, key)
+				delete(f.expires, key)
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "SCAN":
+		pattern := "*"
+		if len(args) >= 3 && strings.ToUpper(args[1]) == "MATCH" {
+			pattern = args[2]
+		}
+		var keys []string
+		for key := range f.values {
+			if ok, _ := path.Match(pattern, key); ok {
+				keys = append(keys, bulk(key))
+			}
+		}
+		return fmt.Sprintf("*2\r\n%s*%d\r\n%s", bulk("0"), len(keys), strings.Join(keys, ""))
+	default:
+		return "-ERR unknown command '" + cmd + "'\r\n"
+	}
+}
+
+// expire removes the keys past their expiration.
+func (f *fakeRedis) expire() {
+	now := time.Now()
+	for key, at := range f.expires {
+		if now.After(at) {
+			delete(f.values, key)
+			delete(f.expires, key)
+		}
+	}
+}
+
+func (f *fakeRedis) ttl(key string) (time.Duration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	at, ok := f.expires[key]
+
+	return time.Until(at), ok
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid command %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
@@ -0,0 +1,424 @@
+package rediscache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache"
+	"golang.org/x/sync/singleflight"
+)
+
+// Options holds the connection settings, zero values select the defaults.
+type Options struct {
+	Password string
+	DB       int
+	// KeyPrefix namespaces the keys, so Clear only removes the items of the cache.
+	KeyPrefix string
+	// PoolSize is the maximum number of idle connections, 10 by default.
+	PoolSize int
+	// Timeout limits dialing and every command, 2 seconds by default.
+	Timeout time.Duration
+}
+
+const (
+	defaultPoolSize = 10
+	defaultTimeout  = 2 * time.Second
+	// defaultItemExpiration applies, when the cache is made without an expiration, so items of a shared
+	// server do not outlive the changes made by other replicas forever.
+	defaultItemExpiration = 5 * time.Minute
+	// scanCount is the number of keys Clear asks Redis to look at in a single SCAN.
+	scanCount = 100
+)
+
+// The invalidations are kept next to the items, the NUL byte keeps their keys apart from the keys of the items.
+const (
+	// versionInfix starts the keys holding the version of an item, which Delete changes.
+	versionInfix = "\x00version:"
+	// clearKey holds the version of the whole cache, which Clear changes.
+	clearKey = "\x00clear"
+)
+
+// RedisCache is a Cache of the values of the type V shared by all the replicas using the same Redis server.
+// The items are stored as JSON and expire by the expiration of their Redis keys.
+//
+// Items loaded by GetOrLoad are stored with the versions of the key and of the cache read before the load,
+// Delete and Clear change the versions, so a load running meanwhile, here or in another replica, cannot
+// bring a stale item back: it is stored, but never returned.
+type RedisCache[V any] struct {
+	addr              string
+	options           Options
+	defaultExpiration time.Duration
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+
+	loads    singleflight.Group
+	hits     uint64
+	misses   uint64
+	versions uint64
+}
+
+// item is the stored form of the values.
+type item[V any] struct {
+	// Version is the version the value was loaded at, values put by Set have none and are always valid.
+	Version string `json:"version,omitempty"`
+	Value   V      `json:"value"`
+}
+
+// NewRedisCache connects to the Redis server at addr. Items put with a zero duration expire after
+// defaultExpiration or after five minutes, when it is zero, negative durations never expire.
+func NewRedisCache[V any](addr string, defaultExpiration time.Duration, options Options) (*RedisCache[V], error) {
+	if options.PoolSize <= 0 {
+		options.PoolSize = defaultPoolSize
+	}
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+
+	if defaultExpiration == 0 {
+		defaultExpiration = defaultItemExpiration
+	}
+
+	c := &RedisCache[V]{
+		addr:              addr,
+		options:           options,
+		defaultExpiration: defaultExpiration,
+	}
+
+	if _, err := c.do("PING"); err != nil {
+		return nil, fmt.Errorf("connecting to redis error: %w", err)
+	}
+
+	return c, nil
+}
+
+// Close closes the idle connections, connections in use are closed when they are released.
+func (c *RedisCache[V]) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for _, cn := range c.idle {
+		cn.Close()
+	}
+	c.idle = nil
+
+	return nil
+}
+
+// Get return cache item by key.
+// Redis removes expired items itself, so Get never returns ErrItemExpired.
+func (c *RedisCache[V]) Get(key string) (V, error) {
+	value, _, err := c.lookup(key)
+
+	return value, err
+}
+
+// Set puts new item into cache.
+// Errors only make the item missing, so they are logged.
+func (c *RedisCache[V]) Set(key string, value V, duration time.Duration) {
+	c.set(key, item[V]{Value: value}, duration)
+}
+
+// Delete removes item from cache and changes the version of the key, so the items of loads started
+// before are never returned.
+func (c *RedisCache[V]) Delete(key string) {
+	if _, err := c.do(c.setArgs(c.options.KeyPrefix+versionInfix+key, c.newVersion(), c.versionExpiration())...); err != nil {
+		log.Printf("redis delete error: %v", err)
+	}
+
+	if _, err := c.do("DEL", c.options.KeyPrefix+key); err != nil {
+		log.Printf("redis delete error: %v", err)
+	}
+
+	c.loads.Forget(key)
+}
+
+// Clear removes all items with the key prefix of the cache and changes the version of the cache.
+func (c *RedisCache[V]) Clear() {
+	if err := c.clear(); err != nil {
+		log.Printf("redis clear error: %v", err)
+	}
+}
+
+// GetOrLoad returns cache item by key or puts the item returned by the loader into cache.
+// Concurrent misses of the key in this process wait for the loader called by the first of them.
+// When the key or the cache were invalidated during the load, the item is returned, but not cached.
+func (c *RedisCache[V]) GetOrLoad(key string, loader cache.Loader[V]) (V, error) {
+	value, version, err := c.lookup(key)
+	if err == nil {
+		return value, nil
+	}
+
+	result, err, _ := c.loads.Do(key, func() (interface{}, error) {
+		value, err := loader()
+		if err != nil {
+			return value, err
+		}
+
+		// without the version Redis is failing, the item is not stored, as it could be stale.
+		if version != "" {
+			c.set(key, item[V]{Version: version, Value: value}, 0)
+		}
+
+		return value, nil
+	})
+
+	return result.(V), err
+}
+
+// Stats returns the hits and misses of this process and the number of items in Redis.
+// Redis expires and evicts the items on its own, so those are not counted.
+func (c *RedisCache[V]) Stats() cache.Stats {
+	stats := cache.Stats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+
+	err := c.scan(func(keys []string) error {
+		for _, key := range keys {
+			if !strings.HasPrefix(key, c.options.KeyPrefix+"\x00") {
+				stats.Entries++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("redis stats error: %v", err)
+	}
+
+	return stats
+}
+
+// lookup returns the item and the current version of the key, which is empty, when Redis failed.
+// Items of other versions are missing.
+func (c *RedisCache[V]) lookup(key string) (V, string, error) {
+	var zero V
+
+	reply, err := c.do("MGET", c.options.KeyPrefix+key, c.options.KeyPrefix+versionInfix+key, c.options.KeyPrefix+clearKey)
+	if err != nil {
+		atomic.AddUint64(&c.misses, 1)
+		return zero, "", err
+	}
+
+	replies, ok := reply.([]interface{})
+	if !ok || len(replies) != 3 {
+		atomic.AddUint64(&c.misses, 1)
+		return zero, "", fmt.Errorf("%w: unexpected MGET reply", ErrProtocol)
+	}
+
+	keyVersion, _ := replies[1].([]byte)
+	cacheVersion, _ := replies[2].([]byte)
+	// the slash keeps versions without invalidations from being empty.
+	version := string(cacheVersion) + "/" + string(keyVersion)
+
+	data, ok := replies[0].([]byte)
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return zero, version, cache.ErrKeyNotFound
+	}
+
+	var stored item[V]
+	if err := json.Unmarshal(data, &stored); err != nil {
+		atomic.AddUint64(&c.misses, 1)
+		return zero, version, fmt.Errorf("decoding cached value error: %w", err)
+	}
+
+	if stored.Version != "" && stored.Version != version {
+		atomic.AddUint64(&c.misses, 1)
+		return zero, version, cache.ErrKeyNotFound
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+
+	return stored.Value, version, nil
+}
+
+func (c *RedisCache[V]) set(key string, stored item[V], duration time.Duration) {
+	data, err := json.Marshal(stored)
+	if err != nil {
+		log.Printf("encoding value for redis error: %v", err)
+		return
+	}
+
+	if duration == 0 {
+		duration = c.defaultExpiration
+	}
+
+	if _, err := c.do(c.setArgs(c.options.KeyPrefix+key, string(data), duration)...); err != nil {
+		log.Printf("redis set error: %v", err)
+	}
+}
+
+// setArgs returns the SET command storing value at key for duration, negative durations never expire.
+func (c *RedisCache[V]) setArgs(key, value string, duration time.Duration) []string {
+	args := []string{"SET", key, value}
+	if duration > 0 {
+		ms := duration.Milliseconds()
+		if ms == 0 {
+			ms = 1
+		}
+
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+
+	return args
+}
+
+// newVersion returns a version, which differs from the versions made before by any replica.
+func (c *RedisCache[V]) newVersion() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(atomic.AddUint64(&c.versions, 1), 36)
+}
+
+// versionExpiration returns the expiration of the version of a key. The version has to outlive the items
+// loaded before it changed, which expire after the default expiration.
+func (c *RedisCache[V]) versionExpiration() time.Duration {
+	return 2 * c.defaultExpiration
+}
+
+func (c *RedisCache[V]) clear() error {
+	// the version changes first, so loads finishing during the removal store items of the old version.
+	if _, err := c.do("SET", c.options.KeyPrefix+clearKey, c.newVersion()); err != nil {
+		return err
+	}
+
+	return c.scan(func(keys []string) error {
+		removed := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if key != c.options.KeyPrefix+clearKey {
+				removed = append(removed, key)
+			}
+		}
+
+		if len(removed) == 0 {
+			return nil
+		}
+
+		_, err := c.do(append([]string{"DEL"}, removed...)...)
+
+		return err
+	})
+}
+
+// scan calls fn with every batch of the keys of the cache.
+func (c *RedisCache[V]) scan(fn func(keys []string) error) error {
+	pattern := escapePattern(c.options.KeyPrefix) + "*"
+	cursor := "0"
+
+	for {
+		reply, err := c.do("SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(scanCount))
+		if err != nil {
+			return err
+		}
+
+		page, ok := reply.([]interface{})
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("%w: unexpected SCAN reply", ErrProtocol)
+		}
+
+		next, ok := page[0].([]byte)
+		if !ok {
+			return fmt.Errorf("%w: unexpected SCAN cursor", ErrProtocol)
+		}
+
+		items, _ := page[1].([]interface{})
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			if key, ok := item.([]byte); ok {
+				keys = append(keys, string(key))
+			}
+		}
+
+		if err := fn(keys); err != nil {
+			return err
+		}
+
+		if cursor = string(next); cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// escapePattern escapes the glob characters of s, so it only matches itself in SCAN MATCH.
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// do runs the command on a pooled connection.
+func (c *RedisCache[V]) do(args ...string) (interface{}, error) {
+	cn, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(args...)
+
+	var serverErr ServerError
+	c.put(cn, err == nil || errors.As(err, &serverErr))
+
+	return reply, err
+}
+
+// get returns an idle connection or dials a new one.
+func (c *RedisCache[V]) get() (*conn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+
+		return cn, nil
+	}
+	c.mu.Unlock()
+
+	cn, err := dial(c.addr, c.options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.options.Password != "" {
+		if _, err := cn.do("AUTH", c.options.Password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	if c.options.DB != 0 {
+		if _, err := cn.do("SELECT", strconv.Itoa(c.options.DB)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+// put returns the connection to the pool, broken connections and those above the pool size are closed.
+func (c *RedisCache[V]) put(cn *conn, reusable bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !reusable || c.closed || len(c.idle) >= c.options.PoolSize {
+		cn.Close()
+		return
+	}
+
+	c.idle = append(c.idle, cn)
+}
@@ -0,0 +1,283 @@
+package rediscache_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache"
+	"github.com/DanilLagunov/jokes-api/pkg/cache/rediscache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openCache connects to the Redis server given by REDIS_TEST_ADDR or to an in-process fake.
+// Every cache gets its own key prefix, so the tests do not see each other's items.
+func openCache(t *testing.T, defaultExpiration time.Duration) *rediscache.RedisCache[models.Joke] {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		addr = startFakeRedis(t, "").Addr()
+	}
+
+	c, err := rediscache.NewRedisCache[models.Joke](addr, defaultExpiration, rediscache.Options{KeyPrefix: "test:" + t.Name() + ":"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		c.Clear()
+		c.Close()
+	})
+
+	return c
+}
+
+func TestGetSetDelete(t *testing.T) {
+	c := openCache(t, time.Minute)
+	joke := models.Joke{ID: "1", Title: "First", Body: "first \"quoted\"\r\nbody", Score: 3}
+
+	_, err := c.Get(joke.ID)
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+
+	c.Set(joke.ID, joke, 0)
+	item, err := c.Get(joke.ID)
+	require.NoError(t, err)
+	assert.Equal(t, joke, item)
+
+	c.Delete(joke.ID)
+	_, err = c.Get(joke.ID)
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+
+	c.Delete("unknown")
+}
+
+func TestExpiration(t *testing.T) {
+	c := openCache(t, 100*time.Millisecond)
+
+	c.Set("default", models.Joke{ID: "default"}, 0)
+	c.Set("short", models.Joke{ID: "short"}, 10*time.Millisecond)
+	c.Set("forever", models.Joke{ID: "forever"}, -1)
+
+	time.Sleep(50 * time.Millisecond)
+	_, err := c.Get("short")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+	_, err = c.Get("default")
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = c.Get("default")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+	_, err = c.Get("forever")
+	assert.NoError(t, err, "negative durations never expire")
+}
+
+func TestExpirationMappedToTTL(t *testing.T) {
+	server := startFakeRedis(t, "")
+	c, err := rediscache.NewRedisCache[models.Joke](server.Addr(), time.Minute, rediscache.Options{KeyPrefix: "p:"})
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	ttl, ok := server.ttl("p:1")
+	require.True(t, ok)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	c.Set("2", models.Joke{ID: "2"}, time.Microsecond)
+	ttl, ok = server.ttl("p:2")
+	require.True(t, ok, "durations below a millisecond still expire")
+	assert.LessOrEqual(t, ttl, time.Millisecond)
+}
+
+func TestDefaultExpiration(t *testing.T) {
+	server := startFakeRedis(t, "")
+	c, err := rediscache.NewRedisCache[models.Joke](server.Addr(), 0, rediscache.Options{KeyPrefix: "p:"})
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	ttl, ok := server.ttl("p:1")
+	require.True(t, ok, "items expire without a configured expiration")
+	assert.InDelta(t, 5*time.Minute, ttl, float64(time.Second))
+
+	_, err = c.GetOrLoad("2", func() (models.Joke, error) {
+		return models.Joke{ID: "2"}, nil
+	})
+	require.NoError(t, err)
+	_, ok = server.ttl("p:2")
+	assert.True(t, ok, "loaded items expire")
+}
+
+func TestClear(t *testing.T) {
+	server := startFakeRedis(t, "")
+
+	c, err := rediscache.NewRedisCache[models.Joke](server.Addr(), time.Minute, rediscache.Options{KeyPrefix: "a*"})
+	require.NoError(t, err)
+	defer c.Close()
+
+	other, err := rediscache.NewRedisCache[models.Joke](server.Addr(), time.Minute, rediscache.Options{KeyPrefix: "ab"})
+	require.NoError(t, err)
+	defer other.Close()
+
+	for _, key := range []string{"1", "2", "3"} {
+		c.Set(key, models.Joke{ID: key}, 0)
+		other.Set(key, models.Joke{ID: key}, 0)
+	}
+
+	c.Clear()
+	assert.Equal(t, 0, c.Stats().Entries)
+	assert.Equal(t, 3, other.Stats().Entries, "the prefix is not a pattern")
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := openCache(t, time.Minute)
+	joke := models.Joke{ID: "1", Title: "First"}
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func() (models.Joke, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return joke, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrLoad("1", loader)
+			assert.NoError(t, err)
+			assert.Equal(t, joke, value)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls), "concurrent misses share a load")
+
+	value, err := c.Get("1")
+	require.NoError(t, err)
+	assert.Equal(t, joke, value)
+
+	_, err = c.GetOrLoad("2", func() (models.Joke, error) {
+		return models.Joke{}, errors.New("storage failure")
+	})
+	assert.EqualError(t, err, "storage failure")
+	_, err = c.Get("2")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound, "failed loads are not cached")
+}
+
+func TestGetOrLoadInvalidated(t *testing.T) {
+	c := openCache(t, time.Minute)
+	stale := models.Joke{ID: "1", Title: "Stale"}
+
+	invalidations := []struct {
+		name       string
+		invalidate func()
+	}{
+		{"delete", func() { c.Delete("1") }},
+		{"clear", c.Clear},
+	}
+
+	for _, tc := range invalidations {
+		value, err := c.GetOrLoad("1", func() (models.Joke, error) {
+			// the joke changes, while its old version is loaded.
+			tc.invalidate()
+			return stale, nil
+		})
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, stale, value, "%s: the loaded item is returned", tc.name)
+
+		_, err = c.Get("1")
+		assert.ErrorIs(t, err, cache.ErrKeyNotFound, "%s: the stale item is not cached", tc.name)
+
+		fresh := models.Joke{ID: "1", Title: "Fresh"}
+		value, err = c.GetOrLoad("1", func() (models.Joke, error) {
+			return fresh, nil
+		})
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, fresh, value, tc.name)
+
+		value, err = c.Get("1")
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, fresh, value, "%s: later loads are cached", tc.name)
+
+		c.Delete("1")
+	}
+
+	c.Set("2", models.Joke{ID: "2"}, 0)
+	c.Delete("3")
+	assert.Equal(t, 1, c.Stats().Entries, "versions are no items")
+}
+
+func TestStats(t *testing.T) {
+	c := openCache(t, time.Minute)
+
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	c.Set("2", models.Joke{ID: "2"}, 0)
+
+	_, err := c.Get("1")
+	require.NoError(t, err)
+	_, err = c.Get("3")
+	assert.ErrorIs(t, err, cache.ErrKeyNotFound)
+
+	assert.Equal(t, cache.Stats{Hits: 1, Misses: 1, Entries: 2}, c.Stats())
+}
+
+func TestAuth(t *testing.T) {
+	server := startFakeRedis(t, "secret")
+
+	_, err := rediscache.NewRedisCache[models.Joke](server.Addr(), time.Minute, rediscache.Options{})
+	var serverErr rediscache.ServerError
+	assert.ErrorAs(t, err, &serverErr)
+
+	c, err := rediscache.NewRedisCache[models.Joke](server.Addr(), time.Minute, rediscache.Options{Password: "secret", DB: 1})
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.Set("1", models.Joke{ID: "1"}, 0)
+	_, err = c.Get("1")
+	assert.NoError(t, err)
+}
+
+func TestUnavailableServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	_, err = rediscache.NewRedisCache[models.Joke](addr, time.Minute, rediscache.Options{Timeout: 100 * time.Millisecond})
+	assert.Error(t, err)
+
+	server := startFakeRedis(t, "")
+	c, err := rediscache.NewRedisCache[models.Joke](server.Addr(), time.Minute, rediscache.Options{Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer c.Close()
+
+	server.listener.Close()
+	c.Close()
+
+	joke := models.Joke{ID: "1"}
+	value, err := c.GetOrLoad("1", func() (models.Joke, error) {
+		return joke, nil
+	})
+	require.NoError(t, err, "the storage is used, when redis is down")
+	assert.Equal(t, joke, value)
+}
+
+func TestOtherValueTypes(t *testing.T) {
+	server := startFakeRedis(t, "")
+	c, err := rediscache.NewRedisCache[[]models.Joke](server.Addr(), time.Minute, rediscache.Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	page := []models.Joke{{ID: "1", Title: "First"}, {ID: "2", Title: "Second"}}
+	c.Set("jokes?skip=0", page, 0)
+
+	value, err := c.Get("jokes?skip=0")
+	require.NoError(t, err)
+	assert.Equal(t, page, value)
+}
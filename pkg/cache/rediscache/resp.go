@@ -0,0 +1,161 @@
+package rediscache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrProtocol describes the error when the server reply is not valid RESP.
+var ErrProtocol = errors.New("redis protocol error")
+
+// ServerError is an error reply of the server.
+type ServerError string
+
+func (e ServerError) Error() string {
+	return "redis: " + string(e)
+}
+
+// conn is a connection speaking RESP, the protocol of Redis.
+// Replies are decoded to string (simple strings), int64 (integers), []byte (bulk strings),
+// []interface{} (arrays) and nil (null bulk strings and arrays).
+type conn struct {
+	netConn net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	timeout time.Duration
+}
+
+func dial(addr string, timeout time.Duration) (*conn, error) {
+	netConn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{
+		netConn: netConn,
+		r:       bufio.NewReader(netConn),
+		w:       bufio.NewWriter(netConn),
+		timeout: timeout,
+	}, nil
+}
+
+func (c *conn) Close() error {
+	return c.netConn.Close()
+}
+
+// do sends the command and returns its reply. A ServerError leaves the connection usable,
+// any other error means the connection is broken.
+func (c *conn) do(args ...string) (interface{}, error) {
+	if c.timeout > 0 {
+		if err := c.netConn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.write(args); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := reply.(ServerError); ok {
+		return nil, e
+	}
+
+	return reply, nil
+}
+
+func (c *conn) write(args []string) error {
+	c.w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+
+	for _, arg := range args {
+		c.w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		c.w.WriteString(arg)
+		c.w.WriteString("\r\n")
+	}
+
+	return c.w.Flush()
+}
+
+func (c *conn) read() (interface{}, error) {
+	line, err := c.line()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%w: empty reply", ErrProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return ServerError(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer %q", ErrProtocol, line)
+		}
+
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("%w: invalid bulk length %q", ErrProtocol, line)
+		}
+
+		if n == -1 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("%w: invalid array length %q", ErrProtocol, line)
+		}
+
+		if n == -1 {
+			return nil, nil
+		}
+
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown reply type %q", ErrProtocol, line[0])
+	}
+}
+
+// line reads a line of the reply without its CRLF.
+func (c *conn) line() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("%w: line not terminated by CRLF", ErrProtocol)
+	}
+
+	return line[:len(line)-2], nil
+}
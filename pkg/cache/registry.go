@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// ErrUnknownDriver describes the error when no cache driver is registered by the requested name.
+var ErrUnknownDriver = errors.New("unknown cache driver")
+
+// Factory creates the cache of the jokes from the configuration.
+type Factory func(cfg config.Config) (Cache[models.Joke], error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+func init() {
+	Register("none", func(cfg config.Config) (Cache[models.Joke], error) {
+		return Nop[models.Joke]{}, nil
+	})
+}
+
+// Register makes a cache driver available by the provided name.
+// It panics if Register is called twice with the same name or if factory is nil.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("cache: Register factory is nil")
+	}
+
+	if _, dup := factories[name]; dup {
+		panic("cache: Register called twice for driver " + name)
+	}
+
+	factories[name] = factory
+}
+
+// Drivers returns a sorted list of the names of the registered drivers.
+func Drivers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Open creates the joke cache of the driver registered by the name.
+func Open(name string, cfg config.Config) (Cache[models.Joke], error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w %q, available drivers: %s", ErrUnknownDriver, name, strings.Join(Drivers(), ", "))
+	}
+
+	c, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cache driver %q: %w", name, err)
+	}
+
+	return c, nil
+}
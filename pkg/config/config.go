@@ -1,22 +1,51 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v6"
 )
 
+// ErrMissingSetting describes the error when a setting required by the chosen driver is not set.
+var ErrMissingSetting = errors.New("missing required settings")
+
 // Config struct.
 type Config struct {
 	Port                   int           `env:"PORT" envDefault:"8000"`
 	ReadHeaderTimeout      time.Duration `env:"READ_HEADER_TIMEOUT"`
 	ReadTimeout            time.Duration `env:"READ_TIMEOUT"`
 	WriteTimeout           time.Duration `env:"WRITE_TIMEOUT"`
+	StorageDriver          string        `env:"STORAGE_DRIVER" envDefault:"mongo"`
+	CacheDriver            string        `env:"CACHE_DRIVER" envDefault:"memory"`
 	DbURI                  string        `env:"DB_URI"`
 	DbName                 string        `env:"DB_NAME"`
 	JokesCollection        string        `env:"JOKES_COLLECTION"`
+	FilePath               string        `env:"FILE_PATH"`
+	FileJournal            bool          `env:"FILE_JOURNAL"`
+	FileJournalCompact     int           `env:"FILE_JOURNAL_COMPACT_AFTER" envDefault:"1000"`
+	FileReloadInterval     time.Duration `env:"FILE_RELOAD_INTERVAL" envDefault:"10s"`
+	SQLitePath             string        `env:"SQLITE_PATH"`
+	PostgresDSN            string        `env:"POSTGRES_DSN"`
+	PostgresMaxOpenConns   int           `env:"POSTGRES_MAX_OPEN_CONNS" envDefault:"10"`
+	PostgresMaxIdleConns   int           `env:"POSTGRES_MAX_IDLE_CONNS" envDefault:"5"`
+	PostgresConnLifetime   time.Duration `env:"POSTGRES_CONN_MAX_LIFETIME" envDefault:"30m"`
+	PostgresConnIdleTime   time.Duration `env:"POSTGRES_CONN_MAX_IDLE_TIME" envDefault:"5m"`
 	CacheDefaultExpiration time.Duration `env:"DEFAULT_EXPIRATION"`
 	CacheCleanupInterval   time.Duration `env:"CLEANUP_INTERVAL"`
+	CacheMaxEntries        int           `env:"CACHE_MAX_ENTRIES"`
+	CacheMaxBytes          int64         `env:"CACHE_MAX_BYTES"`
+	CacheEviction          string        `env:"CACHE_EVICTION" envDefault:"lru"`
+	PageCacheExpiration    time.Duration `env:"PAGE_CACHE_EXPIRATION" envDefault:"30s"`
+	PageCacheMaxEntries    int           `env:"PAGE_CACHE_MAX_ENTRIES" envDefault:"1000"`
+	RedisAddr              string        `env:"REDIS_ADDR"`
+	RedisPassword          string        `env:"REDIS_PASSWORD"`
+	RedisDB                int           `env:"REDIS_DB"`
+	RedisKeyPrefix         string        `env:"REDIS_KEY_PREFIX" envDefault:"jokes-api:"`
+	TrustedProxies         []string      `env:"TRUSTED_PROXIES"`
 }
 
 // NewConfig creating a new Config object.
@@ -28,3 +57,29 @@ func NewConfig() (Config, error) {
 
 	return cfg, nil
 }
+
+// Require returns ErrMissingSetting listing every given environment variable, whose setting is not set.
+func (c Config) Require(names ...string) error {
+	value := reflect.ValueOf(c)
+	fields := make(map[string]reflect.Value, value.NumField())
+
+	for i := 0; i < value.NumField(); i++ {
+		name := strings.Split(value.Type().Field(i).Tag.Get("env"), ",")[0]
+		fields[name] = value.Field(i)
+	}
+
+	var missing []string
+
+	for _, name := range names {
+		field, ok := fields[name]
+		if !ok || field.IsZero() {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingSetting, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequire(t *testing.T) {
+	cfg := config.Config{DbURI: "mongodb://localhost:27017", PostgresMaxOpenConns: 10}
+
+	require.NoError(t, cfg.Require())
+	require.NoError(t, cfg.Require("DB_URI", "POSTGRES_MAX_OPEN_CONNS"))
+
+	err := cfg.Require("DB_URI", "DB_NAME", "JOKES_COLLECTION", "UNKNOWN")
+	require.ErrorIs(t, err, config.ErrMissingSetting)
+	assert.EqualError(t, err, "missing required settings: DB_NAME, JOKES_COLLECTION, UNKNOWN")
+}
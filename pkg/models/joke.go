@@ -11,11 +11,14 @@ type Joke struct {
 	Title string `json:"title" bson:"title"`
 	Body  string `json:"body" bson:"body"`
 	Score int    `json:"score" bson:"score"`
+	// Position orders the joke by insertion in its storage, which sets it for the cursors of the listings.
+	// It is no part of the API.
+	Position int64 `json:"-" bson:"-"`
 }
 
 // NewJoke creating a new Joke object.
 func NewJoke(id, title, body string, score int) Joke {
-	return Joke{id, title, body, score}
+	return Joke{ID: id, Title: title, Body: body, Score: score}
 }
 
 // GenerateID is a function to generate ID for the Joke.
@@ -0,0 +1,150 @@
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+// Page is a cached result of a listing, the jokes of the page and the total amount of jokes.
+type Page struct {
+	Jokes []models.Joke
+	Total int
+}
+
+// Storage caches the listings of the wrapped storage.
+//
+// The keys of the pages start with a version, which every mutation bumps, so a mutation invalidates
+// all the cached pages at once: adding a joke or changing a score moves jokes between the pages of
+// every listing. The version lives in the process, so other replicas and changes made behind the
+// storage, like an edited data file, only show up after the expiration of the pages.
+type Storage struct {
+	storage.Storage
+	pages   cache.Cache[Page]
+	version uint64
+}
+
+// NewStorage wraps the storage, so its listings are cached by pages.
+func NewStorage(s storage.Storage, pages cache.Cache[Page]) *Storage {
+	return &Storage{
+		Storage: s,
+		pages:   pages,
+		// the version of a new process never matches the keys of a previous one left in a shared cache.
+		version: uint64(time.Now().UnixNano()),
+	}
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a copy of ctx whose listings are loaded from the storage without caching
+// their pages, like the pages of a random order, whose seed was generated for a single request.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// query describes a listing, its encoding is the key of its pages.
+type query struct {
+	Listing  string
+	Skip     int
+	Limit    int
+	Text     string             `json:",omitempty"`
+	Mode     storage.SearchMode `json:",omitempty"`
+	RandSeed int64              `json:",omitempty"`
+	Filter   storage.Filter
+}
+
+// GetJokes returns the cached page of the listing or loads it from the storage.
+func (s *Storage) GetJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return s.page(ctx, query{Listing: "jokes", Skip: skip, Limit: limit, Filter: filter}, func() ([]models.Joke, int, error) {
+		return s.Storage.GetJokes(ctx, skip, limit, filter)
+	})
+}
+
+// GetJokesByText returns the cached page of the search or loads it from the storage.
+func (s *Storage) GetJokesByText(ctx context.Context, skip, limit int, text string, mode storage.SearchMode, filter storage.Filter) ([]models.Joke, int, error) {
+	q := query{Listing: "search", Skip: skip, Limit: limit, Text: text, Mode: mode, Filter: filter}
+
+	return s.page(ctx, q, func() ([]models.Joke, int, error) {
+		return s.Storage.GetJokesByText(ctx, skip, limit, text, mode, filter)
+	})
+}
+
+// GetRandomJokes returns the cached page of the random order or loads it from the storage.
+// The order is given by randSeed, so its pages can be cached like any other listing.
+func (s *Storage) GetRandomJokes(ctx context.Context, skip, limit int, randSeed int64) ([]models.Joke, int, error) {
+	return s.page(ctx, query{Listing: "random", Skip: skip, Limit: limit, RandSeed: randSeed}, func() ([]models.Joke, int, error) {
+		return s.Storage.GetRandomJokes(ctx, skip, limit, randSeed)
+	})
+}
+
+// GetFunniestJokes returns the cached page of the listing or loads it from the storage.
+func (s *Storage) GetFunniestJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return s.page(ctx, query{Listing: "funniest", Skip: skip, Limit: limit, Filter: filter}, func() ([]models.Joke, int, error) {
+		return s.Storage.GetFunniestJokes(ctx, skip, limit, filter)
+	})
+}
+
+// AddJoke adds the joke to the storage and invalidates the cached pages.
+func (s *Storage) AddJoke(ctx context.Context, title, body string, score int) (models.Joke, error) {
+	defer s.invalidate()
+
+	return s.Storage.AddJoke(ctx, title, body, score)
+}
+
+// UpdateJoke updates the joke in the storage and invalidates the cached pages.
+func (s *Storage) UpdateJoke(ctx context.Context, id, title, body string) (models.Joke, error) {
+	defer s.invalidate()
+
+	return s.Storage.UpdateJoke(ctx, id, title, body)
+}
+
+// DeleteJoke deletes the joke from the storage and invalidates the cached pages.
+func (s *Storage) DeleteJoke(ctx context.Context, id string) error {
+	defer s.invalidate()
+
+	return s.Storage.DeleteJoke(ctx, id)
+}
+
+// IncrementScore changes the score in the storage and invalidates the cached pages.
+func (s *Storage) IncrementScore(ctx context.Context, id string, delta int) (models.Joke, error) {
+	defer s.invalidate()
+
+	return s.Storage.IncrementScore(ctx, id, delta)
+}
+
+// invalidate bumps the version, so the pages cached before are never read again.
+// It runs even after failed mutations, as they might have changed the storage partially.
+func (s *Storage) invalidate() {
+	atomic.AddUint64(&s.version, 1)
+}
+
+func (s *Storage) page(ctx context.Context, q query, load func() ([]models.Joke, int, error)) ([]models.Joke, int, error) {
+	if skip, _ := ctx.Value(noCacheKey{}).(bool); skip {
+		return load()
+	}
+
+	encoded, err := json.Marshal(q)
+	if err != nil {
+		return load()
+	}
+
+	key := strconv.FormatUint(atomic.LoadUint64(&s.version), 10) + ":" + string(encoded)
+
+	page, err := s.pages.GetOrLoad(key, func() (Page, error) {
+		jokes, total, err := load()
+
+		return Page{Jokes: jokes, Total: total}, err
+	})
+	if err != nil {
+		return []models.Joke{}, page.Total, err
+	}
+
+	// the page is shared by the callers, which are free to change the jokes they get.
+	return append([]models.Joke{}, page.Jokes...), page.Total, nil
+}
@@ -0,0 +1,204 @@
+package cached_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/cache/memcache"
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/cached"
+	fs "github.com/DanilLagunov/jokes-api/pkg/storage/file-storage"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counting counts the listings reaching the wrapped storage.
+type counting struct {
+	storage.Storage
+	calls int32
+	err   error
+}
+
+func (c *counting) GetJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if c.err != nil {
+		return []models.Joke{}, 0, c.err
+	}
+
+	return c.Storage.GetJokes(ctx, skip, limit, filter)
+}
+
+func (c *counting) GetJokesByText(ctx context.Context, skip, limit int, text string, mode storage.SearchMode, filter storage.Filter) ([]models.Joke, int, error) {
+	atomic.AddInt32(&c.calls, 1)
+
+	return c.Storage.GetJokesByText(ctx, skip, limit, text, mode, filter)
+}
+
+func (c *counting) GetRandomJokes(ctx context.Context, skip, limit int, randSeed int64) ([]models.Joke, int, error) {
+	atomic.AddInt32(&c.calls, 1)
+
+	return c.Storage.GetRandomJokes(ctx, skip, limit, randSeed)
+}
+
+func (c *counting) Calls() int {
+	return int(atomic.LoadInt32(&c.calls))
+}
+
+func newFileStorage(t *testing.T) storage.Storage {
+	path := filepath.Join(t.TempDir(), "jokes.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0o644))
+
+	s, err := fs.NewFileStorage(path)
+	require.NoError(t, err)
+
+	return s
+}
+
+func newStorage(s storage.Storage) *cached.Storage {
+	return cached.NewStorage(s, memcache.NewMemCache[cached.Page](time.Minute, 0))
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newStorage(newFileStorage(t))
+	})
+}
+
+func TestListingsAreCached(t *testing.T) {
+	ctx := context.Background()
+	inner := &counting{Storage: newFileStorage(t)}
+	s := newStorage(inner)
+
+	joke, err := s.AddJoke(ctx, "First joke", "A horse walks into a bar", 3)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		jokes, total, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []models.Joke{joke}, jokes)
+		assert.Equal(t, 1, total)
+	}
+	assert.Equal(t, 1, inner.Calls(), "repeated listings are cached")
+
+	minScore := 5
+	jokes, _, err := s.GetJokes(ctx, 0, 10, storage.Filter{MinScore: &minScore})
+	require.NoError(t, err)
+	assert.Empty(t, jokes)
+	_, _, err = s.GetJokes(ctx, 1, 10, storage.Filter{})
+	require.NoError(t, err)
+	_, _, err = s.GetJokesByText(ctx, 0, 10, "horse", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, inner.Calls(), "pages of other filters, offsets and listings are cached apart")
+
+	jokes, _, err = s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	jokes[0].Title = "changed by the caller"
+	jokes, _, err = s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, "First joke", jokes[0].Title, "callers do not change the cached pages")
+}
+
+func TestWithoutCache(t *testing.T) {
+	ctx := context.Background()
+	inner := &counting{Storage: newFileStorage(t)}
+	s := newStorage(inner)
+
+	joke, err := s.AddJoke(ctx, "First joke", "A horse walks into a bar", 3)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		jokes, total, err := s.GetRandomJokes(ctx, 0, 10, 42)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Joke{joke}, jokes)
+		assert.Equal(t, 1, total)
+	}
+	assert.Equal(t, 1, inner.Calls(), "pages of a given seed are cached")
+
+	for i := 0; i < 2; i++ {
+		jokes, _, err := s.GetRandomJokes(cached.WithoutCache(ctx), 0, 10, int64(i))
+		require.NoError(t, err)
+		assert.Equal(t, []models.Joke{joke}, jokes)
+	}
+	_, _, err = s.GetJokes(cached.WithoutCache(ctx), 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, inner.Calls(), "pages read without the cache are loaded every time")
+
+	_, _, err = s.GetRandomJokes(ctx, 0, 10, 0)
+	require.NoError(t, err)
+	_, _, err = s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 6, inner.Calls(), "pages read without the cache are not cached")
+}
+
+func TestMutationsInvalidate(t *testing.T) {
+	ctx := context.Background()
+	inner := &counting{Storage: newFileStorage(t)}
+	s := newStorage(inner)
+
+	joke, err := s.AddJoke(ctx, "First joke", "A horse walks into a bar", 3)
+	require.NoError(t, err)
+
+	mutations := []struct {
+		name   string
+		mutate func() error
+		want   func(jokes []models.Joke)
+	}{
+		{"add", func() error {
+			_, err := s.AddJoke(ctx, "Second joke", "The bartender asks why the long face", 35)
+			return err
+		}, func(jokes []models.Joke) {
+			assert.Len(t, jokes, 2)
+		}},
+		{"update", func() error {
+			_, err := s.UpdateJoke(ctx, joke.ID, "Updated joke", joke.Body)
+			return err
+		}, func(jokes []models.Joke) {
+			assert.Equal(t, "Updated joke", jokes[0].Title)
+		}},
+		{"increment score", func() error {
+			_, err := s.IncrementScore(ctx, joke.ID, 10)
+			return err
+		}, func(jokes []models.Joke) {
+			assert.Equal(t, 13, jokes[0].Score)
+		}},
+		{"delete", func() error {
+			return s.DeleteJoke(ctx, joke.ID)
+		}, func(jokes []models.Joke) {
+			assert.Len(t, jokes, 1)
+		}},
+	}
+
+	for _, m := range mutations {
+		_, _, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+		require.NoError(t, err, m.name)
+		calls := inner.Calls()
+
+		require.NoError(t, m.mutate(), m.name)
+
+		jokes, _, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+		require.NoError(t, err, m.name)
+		assert.Equal(t, calls+1, inner.Calls(), "%s: the listing is loaded again", m.name)
+		m.want(jokes)
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	failure := errors.New("storage failure")
+	inner := &counting{Storage: newFileStorage(t), err: failure}
+	s := newStorage(inner)
+
+	for i := 0; i < 2; i++ {
+		jokes, _, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+		assert.ErrorIs(t, err, failure)
+		assert.NotNil(t, jokes)
+	}
+	assert.Equal(t, 2, inner.Calls())
+}
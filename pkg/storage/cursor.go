@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// ErrInvalidCursor describes the error when a cursor can not be decoded or the order of the listing does not
+// support cursors.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the sort key of the last joke of a page. GetJokes continues after the Position of the joke,
+// GetFunniestJokes continues after the Score the joke had on the page and its Position, so new jokes, votes
+// and deleted jokes never make a listing repeat or skip jokes. The joke itself is not looked up again.
+type Cursor struct {
+	Score    int
+	Position int64
+}
+
+// CursorAfter returns the cursor after the joke.
+func CursorAfter(joke models.Joke) Cursor {
+	return Cursor{Score: joke.Score, Position: joke.Position}
+}
+
+// String encodes the cursor into an opaque URL-safe token.
+func (c Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(c.Score) + ":" + strconv.FormatInt(c.Position, 10)))
+}
+
+// ParseCursor decodes a token returned by Cursor.String.
+func ParseCursor(token string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+
+	score, position, found := strings.Cut(string(raw), ":")
+	if !found {
+		return Cursor{}, fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+
+	n, err := strconv.Atoi(score)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed score", ErrInvalidCursor)
+	}
+
+	p, err := strconv.ParseInt(position, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed position", ErrInvalidCursor)
+	}
+
+	return Cursor{Score: n, Position: p}, nil
+}
@@ -0,0 +1,30 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	for _, cursor := range []storage.Cursor{
+		storage.CursorAfter(models.Joke{ID: "5tz52q", Title: "Title", Body: "Body", Score: 44, Position: 7}),
+		{Score: -3, Position: 1 << 40},
+		{},
+	} {
+		token := cursor.String()
+		assert.NotContains(t, token, ":", "tokens are opaque")
+
+		parsed, err := storage.ParseCursor(token)
+		require.NoError(t, err, token)
+		assert.Equal(t, cursor, parsed)
+	}
+
+	for _, token := range []string{"", "not base64!", "NDI", "eDph", "NDI6", "NDI6eA"} {
+		_, err := storage.ParseCursor(token)
+		assert.ErrorIs(t, err, storage.ErrInvalidCursor, token)
+	}
+}
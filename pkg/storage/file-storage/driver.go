@@ -0,0 +1,28 @@
+package fs
+
+import (
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+func init() {
+	storage.Register("file", func(cfg config.Config) (storage.Storage, error) {
+		if err := cfg.Require("FILE_PATH"); err != nil {
+			return nil, err
+		}
+
+		s, err := OpenFileStorage(cfg.FilePath, JournalConfig{
+			Enabled:      cfg.FileJournal,
+			CompactAfter: cfg.FileJournalCompact,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.FileReloadInterval > 0 {
+			s.Watch(cfg.FileReloadInterval)
+		}
+
+		return s, nil
+	})
+}
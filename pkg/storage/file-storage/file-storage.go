@@ -1,52 +1,116 @@
 package fs
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
+	"io"
+	"log"
 	"os"
+	"regexp"
 	"sort"
-	"strings"
-	"time"
+	"sync"
 
 	"github.com/DanilLagunov/jokes-api/pkg/models"
 	"github.com/DanilLagunov/jokes-api/pkg/storage"
 )
 
-// FileStorage struct.
+// JournalConfig holds the settings of the append-only journal, the zero value disables it.
+type JournalConfig struct {
+	Enabled bool
+	// CompactAfter is the number of entries after which the journal is folded into the data file.
+	CompactAfter int
+}
+
+// FileStorage struct. Data must only be accessed through the methods, which guard it with a lock.
 type FileStorage struct {
 	FilePath string
 	Data     []models.Joke
+	mu       sync.RWMutex
+	journal  *journal
+	inverted *invertedIndex
+	version  version
+	// last is the highest position given to a new joke, so positions of deleted jokes are not given again.
+	last int64
+	// broken holds the error of the last reload, while the data file is invalid.
+	broken  error
+	stop    chan struct{}
+	stopped chan struct{}
 }
 
 // NewFileStorage creating a new FileStorage object.
-func NewFileStorage(filePath string) *FileStorage {
-	var storage FileStorage
+func NewFileStorage(filePath string) (*FileStorage, error) {
+	return OpenFileStorage(filePath, JournalConfig{})
+}
 
-	storage.FilePath = filePath
+// OpenFileStorage reads the jokes from the file at filePath. With the journal enabled, writes are appended
+// to filePath + ".journal" and only folded into the data file every journal.CompactAfter entries.
+func OpenFileStorage(filePath string, journal JournalConfig) (*FileStorage, error) {
+	s := &FileStorage{FilePath: filePath}
 
-	err := parseJSON(storage.FilePath, &storage.Data)
+	v, err := statVersion(filePath)
 	if err != nil {
-		return &FileStorage{}
+		return nil, fmt.Errorf("opening file error: %w", err)
+	}
+
+	if err := parseJSON(filePath, &s.Data); err != nil {
+		return nil, fmt.Errorf("reading %s error: %w", filePath, err)
 	}
-	return &storage
+	number(s.Data)
+	s.version = v
+
+	if journal.Enabled {
+		j, data, err := openJournal(filePath+".journal", journal.CompactAfter, s.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		s.journal, s.Data = j, data
+	}
+
+	s.inverted = newInvertedIndex(s.Data)
+
+	return s, nil
 }
 
-// GetJokes method returns the number of jokes given by skip and limit parameters and total amount of jokes.
-func (s *FileStorage) GetJokes(ctx context.Context, skip, seed int) ([]models.Joke, int, error) {
-	if skip > len(s.Data) {
-		return []models.Joke{}, 0, nil
+// Close stops watching the data file, flushes the journal into the data file and closes it.
+func (s *FileStorage) Close() error {
+	s.stopWatching()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil {
+		return nil
 	}
-	if seed > len(s.Data) {
-		return s.Data[skip:len(s.Data)], len(s.Data), nil
+
+	// an invalid data file is kept, the journal holds the writes made before it was found.
+	var err error
+	if s.broken == nil {
+		err = s.compact()
 	}
-	return s.Data[skip : skip+seed], len(s.Data), nil
+	if closeErr := s.journal.close(); err == nil {
+		err = closeErr
+	}
+	s.journal = nil
+
+	return err
+}
+
+// GetJokes method returns the number of jokes given by skip and limit parameters and total amount of jokes.
+func (s *FileStorage) GetJokes(ctx context.Context, skip, seed int, filter storage.Filter) ([]models.Joke, int, error) {
+	return s.list(skip, seed, filter, storage.SortCreated)
 }
 
 // AddJoke method creating new joke.
 func (s *FileStorage) AddJoke(ctx context.Context, title, body string, score int) (models.Joke, error) {
+	if err := s.lockForWrite(); err != nil {
+		return models.Joke{}, err
+	}
+	defer s.mu.Unlock()
+
 	var id string
 CHECK:
 	id, err := models.GenerateID()
@@ -54,104 +118,318 @@ CHECK:
 		return models.Joke{}, fmt.Errorf("ID generating error: %w", err)
 	}
 
-	for i := 0; i < len(s.Data); i++ {
-		if id == s.Data[i].ID {
-			goto CHECK
-		}
+	if s.index(id) >= 0 {
+		goto CHECK
 	}
 
 	joke := models.NewJoke(id, title, body, score)
-	s.Data = append(s.Data, joke)
-
-	rawDataOut, err := json.MarshalIndent(&s.Data, "", "   ")
-	if err != nil {
-		return joke, fmt.Errorf("marshalling error: %w", err)
+	joke.Position = s.last + 1
+	if last := lastPosition(s.Data); last >= s.last {
+		joke.Position = last + 1
 	}
 
-	err = ioutil.WriteFile(s.FilePath, rawDataOut, 0)
-	if err != nil {
-		return joke, fmt.Errorf("cannot write: %w", err)
+	if err := s.commit(entry{Op: opPut, ID: id, Joke: joke}); err != nil {
+		return models.Joke{}, err
 	}
+	s.last = joke.Position
 
 	return joke, nil
 }
 
-// GetJokesByText returns the number jokes, which contain the desired text, given by skip and limit parameters and total amount of jokes.
-func (s *FileStorage) GetJokesByText(ctx context.Context, skip, seed int, text string) ([]models.Joke, int, error) {
+// GetJokesByText returns the number jokes, which match the search query, given by skip and limit parameters and total amount of jokes.
+// In the plain and text modes words match case-insensitively as prefixes of the words in the title or the body and all of them
+// have to match. In the text mode OR separates alternatives and text in double quotes matches as a phrase. In the regex mode
+// the text is a pattern checked with storage.CompilePattern. In the fuzzy mode every word matches the words with a few typos,
+// see storage.MaxEdits. Jokes are ranked by the number of matches.
+func (s *FileStorage) GetJokesByText(ctx context.Context, skip, seed int, text string, mode storage.SearchMode, filter storage.Filter) ([]models.Joke, int, error) {
 	var result []models.Joke
 
-	for _, item := range s.Data {
-		if strings.Contains(item.Title, text) || strings.Contains(item.Body, text) {
-			result = append(result, item)
+	switch mode {
+	case storage.SearchPlain:
+		s.mu.RLock()
+		result = s.inverted.search(plainQuery(text), filter.Field)
+		s.mu.RUnlock()
+	case storage.SearchText:
+		s.mu.RLock()
+		result = s.inverted.search(parseQuery(text), filter.Field)
+		s.mu.RUnlock()
+	case storage.SearchFuzzy:
+		s.mu.RLock()
+		result = s.inverted.searchAny(storage.FuzzyTerms(text, s.inverted.sorted), filter.Field)
+		s.mu.RUnlock()
+	case storage.SearchRegex:
+		re, err := storage.CompilePattern(text)
+		if err != nil {
+			return []models.Joke{}, 0, err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, storage.RegexTimeout)
+		defer cancel()
+
+		if result, err = s.matchPattern(ctx, re, filter.Field); err != nil {
+			return []models.Joke{}, 0, err
 		}
+	default:
+		return []models.Joke{}, 0, fmt.Errorf("%w: %q", storage.ErrUnknownSearchMode, mode)
 	}
-	if len(result) != 0 {
-		if skip > len(result) {
-			return []models.Joke{}, 0, nil
+
+	result = filterJokes(result, filter)
+	s.order(result, filter)
+
+	return paginate(result, skip, seed), len(result), nil
+}
+
+// matchPattern returns the jokes matching re in the field ranked by the number of matches. The scan stops when ctx is done.
+func (s *FileStorage) matchPattern(ctx context.Context, re *regexp.Regexp, field storage.Field) ([]models.Joke, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type match struct {
+		joke  models.Joke
+		count int
+	}
+
+	var matches []match
+
+	for i, item := range s.Data {
+		if i%1000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		count := 0
+		if field != storage.FieldBody {
+			count += len(re.FindAllStringIndex(item.Title, -1))
 		}
-		if seed > len(result) {
-			return s.Data[skip:len(result)], len(result), nil
+		if field != storage.FieldTitle {
+			count += len(re.FindAllStringIndex(item.Body, -1))
 		}
-		return result[skip : skip+seed], len(result), nil
+
+		if count > 0 {
+			matches = append(matches, match{item, count})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].count > matches[j].count
+	})
+
+	result := make([]models.Joke, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.joke)
 	}
-	return result, 0, storage.ErrJokeNotFound
+	return result, nil
 }
 
 // GetJokeByID returns joke that has the same id.
 func (s *FileStorage) GetJokeByID(ctx context.Context, id string) (models.Joke, error) {
-	for _, item := range s.Data {
-		if item.ID == id {
-			return item, nil
-		}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.index(id); i >= 0 {
+		return s.Data[i], nil
 	}
 	return models.Joke{}, storage.ErrJokeNotFound
 }
 
-// GetRandomJokes returns the number of random jokes given by limit parameter and total amount of jokes.
-func (s *FileStorage) GetRandomJokes(ctx context.Context, seed int) ([]models.Joke, int, error) {
-	r := rand.NewSource(time.Now().UnixNano())
-	rnd := rand.New(r)
+// GetRandomJokes returns the number of random jokes, given by skip and limit parameters and total amount of jokes.
+// The jokes are shuffled in the SortRandom order of randSeed, so the same seed pages through the same permutation.
+func (s *FileStorage) GetRandomJokes(ctx context.Context, skip, seed int, randSeed int64) ([]models.Joke, int, error) {
+	return s.list(skip, seed, storage.Filter{Sort: storage.SortRandom, RandSeed: randSeed}, storage.SortRandom)
+}
 
-	random := make([]models.Joke, seed)
+// GetFunniestJokes returns the number of sorted jokes, given by skip and limit parameters and total amount of jokes.
+func (s *FileStorage) GetFunniestJokes(ctx context.Context, skip, seed int, filter storage.Filter) ([]models.Joke, int, error) {
+	return s.list(skip, seed, filter, storage.SortScoreDesc)
+}
 
-	for i := 0; i < seed; i++ {
-		random = append(random, s.Data[rnd.Intn(len(s.Data))])
+// UpdateJoke replaces title and body of the joke that has the same id and returns the updated joke.
+func (s *FileStorage) UpdateJoke(ctx context.Context, id, title, body string) (models.Joke, error) {
+	if err := s.lockForWrite(); err != nil {
+		return models.Joke{}, err
 	}
-	if seed > len(s.Data) {
-		return random[:len(s.Data)], len(s.Data), nil
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return models.Joke{}, storage.ErrJokeNotFound
 	}
-	return random, len(random), nil
+
+	joke := s.Data[i]
+	joke.Title = title
+	joke.Body = body
+
+	return joke, s.commit(entry{Op: opPut, ID: id, Joke: joke})
 }
 
-// GetFunniestJokes returns the number of sorted jokes, given by skip and limit parameters and total amount of jokes.
-func (s *FileStorage) GetFunniestJokes(ctx context.Context, skip, seed int) ([]models.Joke, int, error) {
-	var funniest []models.Joke
+// DeleteJoke removes joke that has the same id.
+func (s *FileStorage) DeleteJoke(ctx context.Context, id string) error {
+	if err := s.lockForWrite(); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
 
-	funniest = append(funniest, s.Data...)
-	sort.Slice(funniest, func(i, j int) (less bool) {
-		return funniest[i].Score > funniest[j].Score
-	})
+	if s.index(id) < 0 {
+		return storage.ErrJokeNotFound
+	}
+	return s.commit(entry{Op: opDelete, ID: id})
+}
+
+// IncrementScore adds delta to the score of the joke that has the same id and returns the updated joke.
+func (s *FileStorage) IncrementScore(ctx context.Context, id string, delta int) (models.Joke, error) {
+	if err := s.lockForWrite(); err != nil {
+		return models.Joke{}, err
+	}
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return models.Joke{}, storage.ErrJokeNotFound
+	}
+
+	joke := s.Data[i]
+	joke.Score += delta
+
+	return joke, s.commit(entry{Op: opPut, ID: id, Joke: joke})
+}
+
+// index returns the position of the joke that has the same id or -1, the caller must hold the lock.
+func (s *FileStorage) index(id string) int {
+	for i := range s.Data {
+		if s.Data[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// commit persists the change before it becomes visible, so a failed write leaves the data untouched.
+// The caller must hold the write lock.
+func (s *FileStorage) commit(e entry) error {
+	if s.journal == nil {
+		data := e.apply(append([]models.Joke{}, s.Data...))
+		if err := s.write(data); err != nil {
+			return err
+		}
+
+		s.Data = data
+		s.inverted.apply(e)
+
+		return nil
+	}
+
+	if err := s.journal.append(e); err != nil {
+		return err
+	}
+	s.Data = e.apply(s.Data)
+	s.inverted.apply(e)
+
+	if s.journal.entries >= s.journal.compactAfter {
+		// the change is already durable in the journal, a failed compaction is retried on the next write.
+		if err := s.compact(); err != nil {
+			log.Printf("compacting journal error: %s", err)
+		}
+	}
+	return nil
+}
+
+// compact writes the data file and empties the journal, the caller must hold the write lock.
+func (s *FileStorage) compact() error {
+	if err := s.write(s.Data); err != nil {
+		return err
+	}
+	return s.journal.reset()
+}
+
+// write replaces the data file and remembers its version, so the watcher does not reload it.
+// The caller must hold the write lock.
+func (s *FileStorage) write(data []models.Joke) error {
+	if err := writeFileAtomic(s.FilePath, data); err != nil {
+		return err
+	}
+
+	if v, err := statVersion(s.FilePath); err == nil {
+		s.version = v
+	}
+	return nil
+}
+
+// filterJokes returns the jokes passing the filter. Without any condition, jokes are returned as they are.
+func filterJokes(jokes []models.Joke, filter storage.Filter) []models.Joke {
+	if filter == (storage.Filter{Field: filter.Field, After: filter.After}) {
+		return jokes
+	}
 
-	if skip > len(funniest) {
-		return []models.Joke{}, 0, nil
+	result := []models.Joke{}
+	for _, joke := range jokes {
+		if filter.Match(joke) {
+			result = append(result, joke)
+		}
 	}
-	if seed > len(funniest) {
-		return funniest[skip:], len(funniest), nil
+	return result
+}
+
+// paginate returns a copy of the jokes given by skip and limit, so callers never share the backing array of the data.
+func paginate(jokes []models.Joke, skip, limit int) []models.Joke {
+	if skip >= len(jokes) || limit <= 0 {
+		return []models.Joke{}
 	}
-	return funniest[skip : skip+seed], len(funniest), nil
+
+	if limit > len(jokes)-skip {
+		limit = len(jokes) - skip
+	}
+	return append([]models.Joke{}, jokes[skip:skip+limit]...)
 }
 
+// parseJSON decodes the list of jokes in the file at path. Syntax and type errors report the line
+// and column they were found at, so a broken file can be fixed without guessing.
 func parseJSON(path string, list *[]models.Joke) error {
-	file, err := os.Open(path)
+	raw, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("0pening file error: %w", err)
 	}
 
-	decoder := json.NewDecoder(file)
+	decoder := json.NewDecoder(bytes.NewReader(raw))
 
-	err = decoder.Decode(&list)
-	if err != nil {
-		return fmt.Errorf("decode error: %w", err)
+	var jokes []models.Joke
+
+	if err := decoder.Decode(&jokes); err != nil {
+		return fmt.Errorf("decode error%s: %w", position(raw, err), err)
 	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decode error at line %d: unexpected data after the list of jokes",
+			line(raw, decoder.InputOffset()))
+	}
+
+	*list = jokes
 	return nil
 }
+
+// position describes where in raw the decoding error err happened.
+func position(raw []byte, err error) string {
+	var offset int64
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return ""
+	}
+
+	if offset > int64(len(raw)) {
+		offset = int64(len(raw))
+	}
+
+	column := offset - int64(bytes.LastIndexByte(raw[:offset], '\n'))
+
+	return fmt.Sprintf(" at line %d, column %d", line(raw, offset), column)
+}
+
+func line(raw []byte, offset int64) int {
+	return bytes.Count(raw[:offset], []byte("\n")) + 1
+}
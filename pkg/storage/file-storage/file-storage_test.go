@@ -0,0 +1,355 @@
+package fs_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	fs "github.com/DanilLagunov/jokes-api/pkg/storage/file-storage"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const requestTimeout time.Duration = time.Second * 2
+
+// emptyFile creates a file holding an empty list of jokes and returns its path.
+func emptyFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "jokes.json")
+	if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// readFile returns the jokes of the file numbered by their positions, like the storage numbers the jokes it reads.
+func readFile(t *testing.T, path string) []models.Joke {
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var jokes []models.Joke
+	require.NoError(t, json.Unmarshal(raw, &jokes))
+
+	for i := range jokes {
+		jokes[i].Position = int64(i) + 1
+	}
+
+	return jokes
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := fs.NewFileStorage(emptyFile(t))
+		require.NoError(t, err)
+
+		return s
+	})
+}
+
+func TestConformanceWithJournal(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := fs.OpenFileStorage(emptyFile(t), fs.JournalConfig{Enabled: true, CompactAfter: 3})
+		require.NoError(t, err)
+		t.Cleanup(func() { s.Close() })
+
+		return s
+	})
+}
+
+func TestOpenFileStorageMissingFile(t *testing.T) {
+	_, err := fs.OpenFileStorage(filepath.Join(t.TempDir(), "missing.json"), fs.JournalConfig{})
+	assert.Error(t, err)
+}
+
+func TestSaveKeepsFileMode(t *testing.T) {
+	path := emptyFile(t)
+	require.NoError(t, os.Chmod(path, 0o640))
+
+	s, err := fs.NewFileStorage(path)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	joke, err := s.AddJoke(ctx, "Title", "Body", 1)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	assert.Equal(t, []models.Joke{joke}, readFile(t, path))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	for _, journal := range []bool{false, true} {
+		path := emptyFile(t)
+
+		s, err := fs.OpenFileStorage(path, fs.JournalConfig{Enabled: journal, CompactAfter: 7})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*requestTimeout)
+		defer cancel()
+
+		joke, err := s.AddJoke(ctx, "Voted", "Body", 0)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 20; i++ {
+			wg.Add(3)
+
+			go func() {
+				defer wg.Done()
+				_, err := s.AddJoke(ctx, "Title", "Body", 0)
+				assert.NoError(t, err)
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := s.IncrementScore(ctx, joke.ID, 1)
+				assert.NoError(t, err)
+			}()
+			go func() {
+				defer wg.Done()
+				_, _, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+				assert.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+
+		result, err := s.GetJokeByID(ctx, joke.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 20, result.Score)
+
+		require.NoError(t, s.Close())
+
+		reopened, err := fs.OpenFileStorage(path, fs.JournalConfig{})
+		require.NoError(t, err)
+
+		_, amount, err := reopened.GetJokes(ctx, 0, 0, storage.Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, 21, amount, "journal=%t", journal)
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	path := emptyFile(t)
+	config := fs.JournalConfig{Enabled: true, CompactAfter: 100}
+
+	s, err := fs.OpenFileStorage(path, config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	first, err := s.AddJoke(ctx, "First", "Body", 0)
+	require.NoError(t, err)
+	second, err := s.AddJoke(ctx, "Second", "Body", 0)
+	require.NoError(t, err)
+	first, err = s.UpdateJoke(ctx, first.ID, "First updated", "Body")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteJoke(ctx, second.ID))
+
+	assert.Empty(t, readFile(t, path), "adds do not rewrite the data file")
+
+	// simulate a crash: the storage is not closed and the last entry is torn.
+	journal, err := os.OpenFile(path+".journal", os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = journal.WriteString(`{"op":"put","id":"torn","jo`)
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	s, err = fs.OpenFileStorage(path, config)
+	require.NoError(t, err)
+
+	result, amount, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []models.Joke{first}, result)
+	assert.Equal(t, 1, amount)
+
+	third, err := s.AddJoke(ctx, "Third", "Body", 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []models.Joke{first, third}, readFile(t, path), "closing compacts the journal")
+
+	info, err := os.Stat(path + ".journal")
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestJournalCompaction(t *testing.T) {
+	path := emptyFile(t)
+
+	s, err := fs.OpenFileStorage(path, fs.JournalConfig{Enabled: true, CompactAfter: 2})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	first, err := s.AddJoke(ctx, "First", "Body", 0)
+	require.NoError(t, err)
+	assert.Empty(t, readFile(t, path))
+
+	second, err := s.AddJoke(ctx, "Second", "Body", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []models.Joke{first, second}, readFile(t, path))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	// a later modification time than the storage has seen, even on file systems with coarse timestamps.
+	tmp := path + ".edit"
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0o644))
+	require.NoError(t, os.Chtimes(tmp, time.Now(), time.Now().Add(time.Minute)))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestNewFileStorageInvalidFile(t *testing.T) {
+	path := emptyFile(t)
+	writeFile(t, path, "[\n  {\"id\": \"a\", \"title\": \"A\"},\n  {\"id\": 7}\n]")
+
+	_, err := fs.NewFileStorage(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3")
+
+	writeFile(t, path, "[]\n{}")
+
+	_, err = fs.NewFileStorage(path)
+	assert.ErrorContains(t, err, "unexpected data after the list of jokes")
+}
+
+func TestReload(t *testing.T) {
+	path := emptyFile(t)
+
+	s, err := fs.NewFileStorage(path)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	writeFile(t, path, `[{"id": "a", "title": "Edited", "body": "Body", "score": 2}]`)
+	require.NoError(t, s.Reload())
+
+	result, err := s.GetJokeByID(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, models.Joke{ID: "a", Title: "Edited", Body: "Body", Score: 2, Position: 1}, result)
+
+	writeFile(t, path, `[{"id": "a", "title": "Broken"`)
+
+	err = s.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "keeping 1 jokes")
+	assert.NoError(t, s.Reload(), "an invalid file is reported once")
+
+	result, err = s.GetJokeByID(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "Edited", result.Title, "the old data is kept")
+}
+
+func TestWriteKeepsExternalChanges(t *testing.T) {
+	for _, journal := range []bool{false, true} {
+		path := emptyFile(t)
+
+		s, err := fs.OpenFileStorage(path, fs.JournalConfig{Enabled: journal, CompactAfter: 1})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		writeFile(t, path, `[{"id": "a", "title": "Edited", "body": "Body", "score": 2}]`)
+
+		joke, err := s.IncrementScore(ctx, "a", 1)
+		require.NoError(t, err, "journal=%t", journal)
+		assert.Equal(t, models.Joke{ID: "a", Title: "Edited", Body: "Body", Score: 3, Position: 1}, joke)
+		assert.Equal(t, []models.Joke{joke}, readFile(t, path))
+
+		require.NoError(t, s.Close())
+	}
+}
+
+func TestInvalidFileRefusesWrites(t *testing.T) {
+	for _, journal := range []bool{false, true} {
+		path := emptyFile(t)
+
+		s, err := fs.OpenFileStorage(path, fs.JournalConfig{Enabled: journal, CompactAfter: 1})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		broken := `[{"id": "a", "title": "Broken"`
+		writeFile(t, path, broken)
+
+		_, err = s.AddJoke(ctx, "Added", "Body", 0)
+		require.Error(t, err, "journal=%t", journal)
+		assert.Contains(t, err.Error(), "writes are refused")
+		_, err = s.AddJoke(ctx, "Added", "Body", 0)
+		assert.Error(t, err, "journal=%t: writes are refused until the file is fixed", journal)
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, broken, string(content), "journal=%t: the invalid file is kept", journal)
+
+		writeFile(t, path, `[{"id": "a", "title": "Fixed", "body": "Body", "score": 2}]`)
+
+		joke, err := s.IncrementScore(ctx, "a", 1)
+		require.NoError(t, err, "journal=%t: writes resume once the file is fixed", journal)
+		assert.Equal(t, models.Joke{ID: "a", Title: "Fixed", Body: "Body", Score: 3, Position: 1}, joke)
+		assert.Equal(t, []models.Joke{joke}, readFile(t, path))
+
+		require.NoError(t, s.Close())
+	}
+}
+
+func TestReloadKeepsJournal(t *testing.T) {
+	path := emptyFile(t)
+
+	s, err := fs.OpenFileStorage(path, fs.JournalConfig{Enabled: true, CompactAfter: 100})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	added, err := s.AddJoke(ctx, "Added", "Body", 0)
+	require.NoError(t, err)
+
+	writeFile(t, path, `[{"id": "a", "title": "Edited", "body": "Body", "score": 2}]`)
+	require.NoError(t, s.Reload())
+
+	result, _, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+
+	// the jokes of the journal follow the jokes of the reloaded file.
+	added.Position = 2
+	assert.Equal(t, []models.Joke{{ID: "a", Title: "Edited", Body: "Body", Score: 2, Position: 1}, added}, result)
+}
+
+func TestWatch(t *testing.T) {
+	path := emptyFile(t)
+
+	s, err := fs.NewFileStorage(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.Watch(10 * time.Millisecond)
+
+	writeFile(t, path, `[{"id": "a", "title": "Edited", "body": "Body", "score": 2}]`)
+
+	assert.Eventually(t, func() bool {
+		_, amount, err := s.GetJokes(context.Background(), 0, 10, storage.Filter{})
+		return err == nil && amount == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,371 @@
+package fs
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+// orOperator separates alternatives in a search query, it has to be written in upper case.
+const orOperator = "OR"
+
+// tokenize splits text into lower case words, everything but letters and digits separates words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// term is a single condition of a query: a word, which matches every token it is a prefix of,
+// or a phrase, which matches its exact tokens in a row.
+type term struct {
+	words  []string
+	phrase bool
+}
+
+// query is a disjunction of conjunctions: a document matches, when it matches every term of any group.
+type query [][]term
+
+// parseQuery parses the search syntax: words are combined with AND, OR separates alternatives
+// and text in double quotes is a phrase. "cat dog OR \"big fish\"" means (cat AND dog) OR "big fish".
+func parseQuery(text string) query {
+	var q query
+
+	var group []term
+
+	closeGroup := func() {
+		if len(group) > 0 {
+			q = append(q, group)
+		}
+		group = nil
+	}
+
+	for i, part := range strings.Split(text, `"`) {
+		// every odd part was enclosed in quotes, an unbalanced quote extends to the end of the text.
+		if i%2 == 1 {
+			if words := tokenize(part); len(words) > 0 {
+				group = append(group, term{words: words, phrase: true})
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(part) {
+			if field == orOperator {
+				closeGroup()
+				continue
+			}
+
+			// a word joined by punctuation, like "don't", is kept together as a phrase.
+			switch words := tokenize(field); len(words) {
+			case 0:
+			case 1:
+				group = append(group, term{words: words})
+			default:
+				group = append(group, term{words: words, phrase: true})
+			}
+		}
+	}
+	closeGroup()
+
+	return q
+}
+
+// plainQuery makes every word of the text a term, which all have to match. Unlike parseQuery
+// it reads no syntax, OR and quotes are words and separators like any other.
+func plainQuery(text string) query {
+	words := tokenize(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	group := make([]term, 0, len(words))
+	for _, word := range words {
+		group = append(group, term{words: []string{word}})
+	}
+
+	return query{group}
+}
+
+// document is a joke in the index. Seq keeps the insertion order, which ranks documents with equal scores.
+// The tokens of the title take the positions below titleLen, the tokens of the body the positions above it.
+type document struct {
+	joke     models.Joke
+	seq      int
+	terms    []string
+	titleLen int
+}
+
+// invertedIndex maps every token to the positions it occurs at in each joke. Title and body are
+// indexed as one text with a gap between them, so phrases do not match across the two.
+type invertedIndex struct {
+	postings map[string]map[string][]int
+	docs     map[string]*document
+	// sorted holds the tokens in order for prefix lookups. It is kept up to date by the writes,
+	// so searches, which run concurrently under a read lock, never modify the index.
+	sorted []string
+	seq    int
+}
+
+func newInvertedIndex(jokes []models.Joke) *invertedIndex {
+	x := &invertedIndex{
+		postings: make(map[string]map[string][]int),
+		docs:     make(map[string]*document, len(jokes)),
+	}
+
+	for _, joke := range jokes {
+		x.put(joke)
+	}
+
+	x.sorted = make([]string, 0, len(x.postings))
+	for token := range x.postings {
+		x.sorted = append(x.sorted, token)
+	}
+	sort.Strings(x.sorted)
+
+	return x
+}
+
+// put adds the joke or replaces the joke with the same id, which keeps its place in the insertion order.
+func (x *invertedIndex) put(joke models.Joke) {
+	seq := x.seq
+
+	if doc, ok := x.docs[joke.ID]; ok {
+		seq = doc.seq
+		x.remove(joke.ID)
+	} else {
+		x.seq++
+	}
+
+	title := tokenize(joke.Title)
+	tokens := append(append(title, ""), tokenize(joke.Body)...)
+
+	doc := &document{joke: joke, seq: seq, titleLen: len(title)}
+
+	for pos, token := range tokens {
+		if token == "" {
+			continue
+		}
+
+		posting, ok := x.postings[token]
+		if !ok {
+			posting = make(map[string][]int)
+			x.postings[token] = posting
+			x.insertSorted(token)
+		}
+
+		if len(posting[joke.ID]) == 0 {
+			doc.terms = append(doc.terms, token)
+		}
+		posting[joke.ID] = append(posting[joke.ID], pos)
+	}
+
+	x.docs[joke.ID] = doc
+}
+
+func (x *invertedIndex) remove(id string) {
+	doc, ok := x.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, token := range doc.terms {
+		delete(x.postings[token], id)
+
+		if len(x.postings[token]) == 0 {
+			delete(x.postings, token)
+			x.removeSorted(token)
+		}
+	}
+	delete(x.docs, id)
+}
+
+// apply keeps the index in sync with a journal entry.
+func (x *invertedIndex) apply(e entry) {
+	if e.Op == opDelete {
+		x.remove(e.ID)
+		return
+	}
+	x.put(e.Joke)
+}
+
+// search returns the jokes matching q in the field, ranked by the number of times the terms occur in them.
+func (x *invertedIndex) search(q query, field storage.Field) []models.Joke {
+	scores := make(map[string]int)
+
+	for _, group := range q {
+		for id, score := range x.matchGroup(group, field) {
+			scores[id] += score
+		}
+	}
+	return x.rank(scores)
+}
+
+// searchAny returns the jokes containing any of the tokens of every group in the field, ranked by
+// the number of times the tokens occur in them. The tokens match exactly.
+func (x *invertedIndex) searchAny(groups [][]string, field storage.Field) []models.Joke {
+	var scores map[string]int
+
+	for _, tokens := range groups {
+		counts := make(map[string]int)
+		for _, token := range tokens {
+			x.count(counts, token, field)
+		}
+
+		scores = intersect(scores, counts)
+		if len(scores) == 0 {
+			break
+		}
+	}
+	return x.rank(scores)
+}
+
+// rank returns the jokes by their scores, highest first, equal scores keep the insertion order.
+func (x *invertedIndex) rank(scores map[string]int) []models.Joke {
+	docs := make([]*document, 0, len(scores))
+	for id := range scores {
+		docs = append(docs, x.docs[id])
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		si, sj := scores[docs[i].joke.ID], scores[docs[j].joke.ID]
+		if si != sj {
+			return si > sj
+		}
+		return docs[i].seq < docs[j].seq
+	})
+
+	result := make([]models.Joke, 0, len(docs))
+	for _, doc := range docs {
+		result = append(result, doc.joke)
+	}
+	return result
+}
+
+// matchGroup returns the score of every document matching all terms of the group.
+func (x *invertedIndex) matchGroup(group []term, field storage.Field) map[string]int {
+	var scores map[string]int
+
+	for _, t := range group {
+		scores = intersect(scores, x.matchTerm(t, field))
+
+		if len(scores) == 0 {
+			break
+		}
+	}
+	return scores
+}
+
+// intersect keeps the documents of scores, which are in counts too, and adds their counts.
+// A nil scores is the first condition, it takes counts as they are.
+func intersect(scores, counts map[string]int) map[string]int {
+	if scores == nil {
+		return counts
+	}
+
+	for id := range scores {
+		if count, ok := counts[id]; ok {
+			scores[id] += count
+		} else {
+			delete(scores, id)
+		}
+	}
+	return scores
+}
+
+// matchTerm returns the number of occurrences of the term in every document that contains it.
+func (x *invertedIndex) matchTerm(t term, field storage.Field) map[string]int {
+	counts := make(map[string]int)
+
+	if !t.phrase {
+		for _, token := range x.withPrefix(t.words[0]) {
+			x.count(counts, token, field)
+		}
+		return counts
+	}
+
+	for id, positions := range x.postings[t.words[0]] {
+		for _, start := range positions {
+			if x.docs[id].inField(start, field) && x.phraseAt(id, t.words[1:], start+1) {
+				counts[id]++
+			}
+		}
+	}
+	return counts
+}
+
+// count adds the occurrences of the token in the field to the counts of the documents.
+func (x *invertedIndex) count(counts map[string]int, token string, field storage.Field) {
+	for id, positions := range x.postings[token] {
+		if field == storage.FieldAny {
+			counts[id] += len(positions)
+			continue
+		}
+
+		doc := x.docs[id]
+		for _, pos := range positions {
+			if doc.inField(pos, field) {
+				counts[id]++
+			}
+		}
+	}
+}
+
+// inField reports whether the token at position pos belongs to the field.
+func (doc *document) inField(pos int, field storage.Field) bool {
+	switch field {
+	case storage.FieldTitle:
+		return pos < doc.titleLen
+	case storage.FieldBody:
+		return pos > doc.titleLen
+	default:
+		return true
+	}
+}
+
+// phraseAt reports whether the document contains the words in a row starting at position pos.
+func (x *invertedIndex) phraseAt(id string, words []string, pos int) bool {
+	for i, word := range words {
+		if !containsInt(x.postings[word][id], pos+i) {
+			return false
+		}
+	}
+	return true
+}
+
+// insertSorted adds a new token to the sorted tokens. While the index is built, sorted is nil
+// and the tokens are sorted once at the end.
+func (x *invertedIndex) insertSorted(token string) {
+	if x.sorted == nil {
+		return
+	}
+
+	i := sort.SearchStrings(x.sorted, token)
+	x.sorted = append(x.sorted, "")
+	copy(x.sorted[i+1:], x.sorted[i:])
+	x.sorted[i] = token
+}
+
+func (x *invertedIndex) removeSorted(token string) {
+	if i := sort.SearchStrings(x.sorted, token); i < len(x.sorted) && x.sorted[i] == token {
+		x.sorted = append(x.sorted[:i], x.sorted[i+1:]...)
+	}
+}
+
+// withPrefix returns the indexed tokens starting with prefix.
+func (x *invertedIndex) withPrefix(prefix string) []string {
+	var tokens []string
+
+	for i := sort.SearchStrings(x.sorted, prefix); i < len(x.sorted) && strings.HasPrefix(x.sorted[i], prefix); i++ {
+		tokens = append(tokens, x.sorted[i])
+	}
+	return tokens
+}
+
+// containsInt reports whether the sorted positions contain pos.
+func containsInt(positions []int, pos int) bool {
+	i := sort.SearchInts(positions, pos)
+	return i < len(positions) && positions[i] == pos
+}
@@ -0,0 +1,83 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want query
+	}{
+		{"", nil},
+		{"  ...  ", nil},
+		{"Cat", query{{{words: []string{"cat"}}}}},
+		{"cat dog", query{{{words: []string{"cat"}}, {words: []string{"dog"}}}}},
+		{"cat OR dog", query{{{words: []string{"cat"}}}, {{words: []string{"dog"}}}}},
+		{"cat or dog", query{{{words: []string{"cat"}}, {words: []string{"or"}}, {words: []string{"dog"}}}}},
+		{`cat OR "Big, fish"`, query{{{words: []string{"cat"}}}, {{words: []string{"big", "fish"}, phrase: true}}}},
+		{`don't`, query{{{words: []string{"don", "t"}, phrase: true}}}},
+		{`"big fish`, query{{{words: []string{"big", "fish"}, phrase: true}}}},
+		{"OR cat OR", query{{{words: []string{"cat"}}}}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseQuery(tt.text), tt.text)
+	}
+}
+
+func TestPlainQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want query
+	}{
+		{"", nil},
+		{"  ...  ", nil},
+		{"cat OR dog", query{{{words: []string{"cat"}}, {words: []string{"or"}}, {words: []string{"dog"}}}}},
+		{`"Big, fish"`, query{{{words: []string{"big"}}, {words: []string{"fish"}}}}},
+		{`don't .net`, query{{{words: []string{"don"}}, {words: []string{"t"}}, {words: []string{"net"}}}}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, plainQuery(tt.text), tt.text)
+	}
+}
+
+func TestInvertedIndexSearch(t *testing.T) {
+	x := newInvertedIndex([]models.Joke{
+		models.NewJoke("a", "Cats", "A cat and a dog walk into a bar.", 0),
+		models.NewJoke("b", "Dogs", "The dog, the dog and the other dog!", 0),
+		models.NewJoke("c", "Fish", "A big fish. Big-fish.", 0),
+		models.NewJoke("d", "Big", "Fish are friends", 0),
+	})
+
+	ids := func(text string) []string {
+		result := []string{}
+		for _, joke := range x.search(parseQuery(text), storage.FieldAny) {
+			result = append(result, joke.ID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"b", "a"}, ids("DOG"), "ranked by term frequency")
+	assert.Equal(t, []string{"a"}, ids("cat dog"), "all words have to match")
+	assert.Equal(t, []string{"a"}, ids("ca"), "words match as prefixes")
+	assert.Equal(t, []string{"b", "c", "a", "d"}, ids("dog OR fish"), "ties keep the insertion order")
+	assert.Equal(t, []string{"c"}, ids(`"big fish"`), "phrases do not span title and body")
+	assert.Equal(t, []string{}, ids(`"friends fish"`))
+	assert.Equal(t, []string{}, ids("penguin"))
+	assert.Equal(t, []string{}, ids(""))
+
+	x.put(models.NewJoke("a", "Cats", "No dogs allowed", 0))
+	assert.Equal(t, []string{}, ids("walk"), "replaced jokes are reindexed")
+	assert.Equal(t, []string{"a"}, ids("allowed"))
+	assert.Equal(t, []string{"a", "b"}, ids("dogs"), "replaced jokes keep their place")
+
+	x.remove("b")
+	assert.Equal(t, []string{"a"}, ids("dog"))
+	assert.NotContains(t, x.sorted, "other", "tokens of removed jokes are dropped")
+}
@@ -0,0 +1,129 @@
+package fs
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+// list returns the page of the jokes passing the filter in its order, def is the order of the listing.
+func (s *FileStorage) list(skip, limit int, filter storage.Filter, def storage.Sort) ([]models.Joke, int, error) {
+	order, err := storage.ListingSort(filter, def)
+	if err != nil {
+		return []models.Joke{}, 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jokes := filterJokes(s.Data, filter)
+	amount := len(jokes)
+
+	// the data is in insertion order already, other orders sort a copy.
+	if order == storage.SortCreated {
+		if after := filter.After; after != nil {
+			jokes = jokes[sort.Search(len(jokes), func(i int) bool { return jokes[i].Position > after.Position }):]
+		}
+
+		return paginate(jokes, skip, limit), amount, nil
+	}
+
+	jokes = append([]models.Joke{}, jokes...)
+	keys := sortJokes(jokes, order, filter.RandSeed)
+
+	if after := filter.After; after != nil {
+		// the cursor keeps the score the joke had on the page, the orders of cursors need no other keys.
+		last := sortKey{score: after.Score, position: after.Position}
+
+		first := sort.Search(len(jokes), func(i int) bool {
+			return last.less(keys[i], order)
+		})
+		jokes = jokes[first:]
+	}
+
+	return paginate(jokes, skip, limit), amount, nil
+}
+
+// order sorts found jokes, which are ranked by relevance, in the order of the filter.
+func (s *FileStorage) order(jokes []models.Joke, filter storage.Filter) {
+	if filter.Sort == storage.SortDefault || filter.Sort == storage.SortRelevance {
+		return
+	}
+
+	sortJokes(jokes, filter.Sort, filter.RandSeed)
+}
+
+// sortKey holds what orders a joke, it is computed once for every joke before sorting.
+type sortKey struct {
+	score    int
+	title    string
+	shuffle  int64
+	position int64
+}
+
+func newSortKey(joke models.Joke, order storage.Sort, seed int64) sortKey {
+	key := sortKey{score: joke.Score, position: joke.Position}
+
+	switch order {
+	case storage.SortTitle:
+		key.title = strings.ToLower(joke.Title)
+	case storage.SortRandom:
+		key.shuffle = storage.ShuffleKey(seed, joke.ID)
+	}
+
+	return key
+}
+
+// less reports whether the joke of k goes before the joke of o in the order, ties are broken by the positions.
+func (k sortKey) less(o sortKey, order storage.Sort) bool {
+	switch order {
+	case storage.SortScore:
+		if k.score != o.score {
+			return k.score < o.score
+		}
+	case storage.SortScoreDesc:
+		if k.score != o.score {
+			return k.score > o.score
+		}
+	case storage.SortTitle:
+		if k.title != o.title {
+			return k.title < o.title
+		}
+	case storage.SortRandom:
+		if k.shuffle != o.shuffle {
+			return k.shuffle < o.shuffle
+		}
+	}
+
+	return k.position < o.position
+}
+
+// byKey sorts the jokes together with their keys.
+type byKey struct {
+	jokes []models.Joke
+	keys  []sortKey
+	order storage.Sort
+}
+
+func (b byKey) Len() int { return len(b.jokes) }
+
+func (b byKey) Less(i, j int) bool { return b.keys[i].less(b.keys[j], b.order) }
+
+func (b byKey) Swap(i, j int) {
+	b.jokes[i], b.jokes[j] = b.jokes[j], b.jokes[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// sortJokes sorts the jokes in the order and returns their keys in the same order.
+func sortJokes(jokes []models.Joke, order storage.Sort, seed int64) []sortKey {
+	keys := make([]sortKey, len(jokes))
+	for i, joke := range jokes {
+		keys[i] = newSortKey(joke, order, seed)
+	}
+
+	sort.Sort(byKey{jokes: jokes, keys: keys, order: order})
+
+	return keys
+}
@@ -0,0 +1,252 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// defaultCompactAfter is the number of journal entries after which the journal is folded into the data file.
+const defaultCompactAfter = 1000
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// entry is a single journal record. A put stores the whole joke, so replaying entries is idempotent
+// and a crash between compacting the data file and truncating the journal loses nothing.
+type entry struct {
+	Op   string      `json:"op"`
+	ID   string      `json:"id"`
+	Joke models.Joke `json:"joke"`
+}
+
+// apply returns data with the entry applied. The data may be modified in place.
+// The files keep no positions, a changed joke keeps its own and a new joke without one follows the last joke.
+func (e entry) apply(data []models.Joke) []models.Joke {
+	joke := e.Joke
+
+	for i := range data {
+		if data[i].ID != e.ID {
+			continue
+		}
+
+		if e.Op == opDelete {
+			return append(data[:i], data[i+1:]...)
+		}
+
+		joke.Position = data[i].Position
+		data[i] = joke
+		return data
+	}
+
+	if e.Op == opPut {
+		if last := lastPosition(data); joke.Position <= last {
+			joke.Position = last + 1
+		}
+		data = append(data, joke)
+	}
+	return data
+}
+
+// number gives the jokes read from the data file their positions in the file.
+func number(data []models.Joke) {
+	for i := range data {
+		data[i].Position = int64(i) + 1
+	}
+}
+
+// lastPosition returns the position of the last joke or 0 without jokes.
+func lastPosition(data []models.Joke) int64 {
+	if len(data) == 0 {
+		return 0
+	}
+	return data[len(data)-1].Position
+}
+
+// journal is an append-only file of entries, which makes a write cost one line instead of the whole data file.
+type journal struct {
+	file         *os.File
+	entries      int
+	compactAfter int
+}
+
+// openJournal opens the journal at path, applies its entries to data and returns the updated data.
+// A torn last line left by a crash is cut off, so the following appends start on a clean line.
+func openJournal(path string, compactAfter int, data []models.Joke) (*journal, []models.Joke, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, data, fmt.Errorf("opening journal error: %w", err)
+	}
+
+	if compactAfter <= 0 {
+		compactAfter = defaultCompactAfter
+	}
+
+	j := &journal{file: file, compactAfter: compactAfter}
+
+	data, offset, err := j.replay(data)
+	if err != nil {
+		file.Close()
+		return nil, data, err
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, data, fmt.Errorf("truncating journal error: %w", err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, data, fmt.Errorf("seeking journal error: %w", err)
+	}
+
+	return j, data, nil
+}
+
+// replay applies the entries of the journal to data. It returns the updated data and
+// the offset after the last valid entry.
+func (j *journal) replay(data []models.Joke) ([]models.Joke, int64, error) {
+	info, err := j.file.Stat()
+	if err != nil {
+		return data, 0, fmt.Errorf("reading journal error: %w", err)
+	}
+
+	var offset int64
+
+	j.entries = 0
+
+	reader := bufio.NewReader(io.NewSectionReader(j.file, 0, info.Size()))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				log.Printf("journal %s: dropping torn entry at offset %d", j.file.Name(), offset)
+			}
+			break
+		}
+		if err != nil {
+			return data, offset, fmt.Errorf("reading journal error: %w", err)
+		}
+
+		var e entry
+		if err := json.Unmarshal(bytes.TrimSpace(line), &e); err != nil {
+			log.Printf("journal %s: dropping corrupt entries from offset %d: %s", j.file.Name(), offset, err)
+			break
+		}
+
+		data = e.apply(data)
+		offset += int64(len(line))
+		j.entries++
+	}
+
+	return data, offset, nil
+}
+
+// append writes the entry and flushes it to disk.
+func (j *journal) append(e entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshalling error: %w", err)
+	}
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing journal error: %w", err)
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("syncing journal error: %w", err)
+	}
+
+	j.entries++
+	return nil
+}
+
+// reset empties the journal after its entries were written to the data file.
+func (j *journal) reset() error {
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncating journal error: %w", err)
+	}
+
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking journal error: %w", err)
+	}
+
+	j.entries = 0
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+// writeFileAtomic replaces the file at path with the jokes. The data is written to a temporary file
+// in the same directory, flushed and renamed over the old file, so readers and crashes only ever see
+// either the old or the new content.
+func writeFileAtomic(path string, jokes []models.Joke) error {
+	raw, err := json.MarshalIndent(jokes, "", "   ")
+	if err != nil {
+		return fmt.Errorf("marshalling error: %w", err)
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cannot write: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write: %w", err)
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot sync: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot replace: %w", err)
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes the directory entry of a renamed file. Platforms that cannot sync directories are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		log.Printf("syncing directory %s error: %s", dir, err)
+	}
+}
@@ -0,0 +1,195 @@
+package fs
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// version identifies the content of the data file, so changes made by other programs can be told
+// apart from the writes of the storage itself.
+type version struct {
+	modTime time.Time
+	size    int64
+}
+
+func statVersion(path string) (version, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return version{}, err
+	}
+	return version{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Watch polls the data file every interval and reloads it when it was changed by another program.
+// Watching stops when the storage is closed, calling Watch again while watching does nothing.
+func (s *FileStorage) Watch(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go s.watch(interval, s.stop, s.stopped)
+}
+
+func (s *FileStorage) watch(interval time.Duration, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.Reload(); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
+// stopWatching stops the watcher and waits for it to exit, the caller must not hold the lock.
+func (s *FileStorage) stopWatching() {
+	s.mu.Lock()
+	stop, stopped := s.stop, s.stopped
+	s.stop, s.stopped = nil, nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+}
+
+// maxReadAttempts limits the reads of a data file, which keeps changing while it is read outside the lock,
+// before writes read it under the lock.
+const maxReadAttempts = 3
+
+// snapshot is the data file read outside the lock, which is swapped in under the lock.
+type snapshot struct {
+	version version
+	// changed reports that the file is not the one loaded, only then data or err are set.
+	changed bool
+	data    []models.Joke
+	err     error
+}
+
+// Reload reads the data file again if it changed since it was last read or written and swaps in
+// the new jokes. Pending journal entries are applied on top of them, just like on a restart.
+// When the file is invalid, the current data is kept and the error describes the problem;
+// the same invalid file is reported only once. Writes fail, until a valid file is loaded,
+// so they never overwrite the file with the data it was meant to replace.
+func (s *FileStorage) Reload() error {
+	snap, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the file changed again during the read, it is picked up by the next reload.
+	if current, err := statVersion(s.FilePath); err != nil || current != snap.version {
+		return nil
+	}
+
+	return s.swap(snap)
+}
+
+// read parses the data file without the lock, when it is not the loaded one.
+func (s *FileStorage) read() (snapshot, error) {
+	seen, err := statVersion(s.FilePath)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("reloading %s error: %w", s.FilePath, err)
+	}
+
+	s.mu.RLock()
+	loaded := s.version
+	s.mu.RUnlock()
+
+	if seen == loaded {
+		return snapshot{version: seen}, nil
+	}
+
+	snap := snapshot{version: seen, changed: true}
+	if snap.err = parseJSON(s.FilePath, &snap.data); snap.err == nil {
+		number(snap.data)
+	}
+
+	return snap, nil
+}
+
+// swap loads the snapshot unless it is loaded already, the caller must hold the write lock.
+// An invalid snapshot is kept in broken, which refuses the writes.
+func (s *FileStorage) swap(snap snapshot) error {
+	if !snap.changed || snap.version == s.version {
+		return nil
+	}
+	s.version = snap.version
+
+	data, err := snap.data, snap.err
+	if err == nil && s.journal != nil {
+		data, _, err = s.journal.replay(data)
+	}
+
+	if err != nil {
+		s.broken = fmt.Errorf("reloading %s error, keeping %d jokes: %w", s.FilePath, len(s.Data), err)
+		return s.broken
+	}
+
+	log.Printf("reloaded %s: %d jokes", s.FilePath, len(data))
+	s.Data = data
+	s.inverted = newInvertedIndex(data)
+	s.broken = nil
+
+	return nil
+}
+
+// lockForWrite takes the write lock and picks up changes of the data file made by other programs first,
+// so a write never overwrites them. It fails without holding the lock, when the data file is invalid.
+func (s *FileStorage) lockForWrite() error {
+	for attempt := 1; ; attempt++ {
+		snap, err := s.read()
+
+		s.mu.Lock()
+
+		if err == nil {
+			current, statErr := statVersion(s.FilePath)
+
+			switch {
+			case statErr == nil && current != snap.version && attempt < maxReadAttempts:
+				// the file changed during the read, the read is repeated.
+				s.mu.Unlock()
+				continue
+			case statErr == nil && current != snap.version:
+				snap = snapshot{version: current, changed: true}
+				if snap.err = parseJSON(s.FilePath, &snap.data); snap.err == nil {
+					number(snap.data)
+				}
+			}
+
+			if err := s.swap(snap); err != nil {
+				log.Print(err)
+			}
+		} else {
+			log.Print(err)
+		}
+
+		if s.broken != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("%w: writes are refused until the file is fixed", s.broken)
+		}
+
+		return nil
+	}
+}
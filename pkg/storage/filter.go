@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// Field selects the part of the jokes the search text has to match.
+type Field string
+
+const (
+	// FieldAny matches the search text in the title or the body. It is the default.
+	FieldAny Field = ""
+	// FieldTitle matches the search text in the title only.
+	FieldTitle Field = "title"
+	// FieldBody matches the search text in the body only.
+	FieldBody Field = "body"
+)
+
+// ErrInvalidFilter describes the error when a filter can not match any joke or has an unknown field.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter narrows down and orders the jokes of a listing, nil and zero fields do not filter. Body lengths are counted in characters.
+type Filter struct {
+	MinScore *int
+	MaxScore *int
+	// Field restricts the text of GetJokesByText to the title or the body, the other listings ignore it.
+	Field         Field
+	MinBodyLength *int
+	MaxBodyLength *int
+	HasBody       *bool
+	// After continues GetJokes and GetFunniestJokes after the cursor, skip then counts from it.
+	// The total amount still counts every joke passing the filter. GetJokesByText ignores it, as
+	// relevance is no stable order.
+	After *Cursor
+	// Sort orders the listing, RandSeed gives the order of SortRandom.
+	Sort     Sort
+	RandSeed int64
+}
+
+// ParseField returns the field named s, the empty string selects FieldAny.
+func ParseField(s string) (Field, error) {
+	switch field := Field(s); field {
+	case FieldAny, FieldTitle, FieldBody:
+		return field, nil
+	default:
+		return "", fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, s)
+	}
+}
+
+// Validate reports the ranges of the filter, which are empty or negative, and unknown fields and sorts.
+func (f Filter) Validate() error {
+	if f.MinScore != nil && f.MaxScore != nil && *f.MinScore > *f.MaxScore {
+		return fmt.Errorf("%w: the minimal score is greater than the maximal score", ErrInvalidFilter)
+	}
+
+	if (f.MinBodyLength != nil && *f.MinBodyLength < 0) || (f.MaxBodyLength != nil && *f.MaxBodyLength < 0) {
+		return fmt.Errorf("%w: body lengths can not be negative", ErrInvalidFilter)
+	}
+
+	if f.MinBodyLength != nil && f.MaxBodyLength != nil && *f.MinBodyLength > *f.MaxBodyLength {
+		return fmt.Errorf("%w: the minimal body length is greater than the maximal body length", ErrInvalidFilter)
+	}
+
+	if _, err := ParseField(string(f.Field)); err != nil {
+		return err
+	}
+
+	if _, err := ParseSort(string(f.Sort)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Match reports whether the joke passes the score, body length and body presence conditions of the filter.
+func (f Filter) Match(joke models.Joke) bool {
+	if f.MinScore != nil && joke.Score < *f.MinScore {
+		return false
+	}
+
+	if f.MaxScore != nil && joke.Score > *f.MaxScore {
+		return false
+	}
+
+	if f.HasBody != nil && *f.HasBody != (joke.Body != "") {
+		return false
+	}
+
+	if f.MinBodyLength == nil && f.MaxBodyLength == nil {
+		return true
+	}
+
+	length := utf8.RuneCountInString(joke.Body)
+
+	return (f.MinBodyLength == nil || length >= *f.MinBodyLength) && (f.MaxBodyLength == nil || length <= *f.MaxBodyLength)
+}
@@ -0,0 +1,82 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestParseField(t *testing.T) {
+	for s, want := range map[string]storage.Field{
+		"":      storage.FieldAny,
+		"title": storage.FieldTitle,
+		"body":  storage.FieldBody,
+	} {
+		field, err := storage.ParseField(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, field, s)
+	}
+
+	_, err := storage.ParseField("score")
+	assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+}
+
+func TestFilterValidate(t *testing.T) {
+	for _, f := range []storage.Filter{
+		{},
+		{MinScore: intPtr(-5), MaxScore: intPtr(-5)},
+		{MinBodyLength: intPtr(0), MaxBodyLength: intPtr(10)},
+		{Field: storage.FieldBody, HasBody: boolPtr(false)},
+	} {
+		assert.NoError(t, f.Validate(), "%+v", f)
+	}
+
+	for _, f := range []storage.Filter{
+		{MinScore: intPtr(2), MaxScore: intPtr(1)},
+		{MinBodyLength: intPtr(-1)},
+		{MaxBodyLength: intPtr(-1)},
+		{MinBodyLength: intPtr(5), MaxBodyLength: intPtr(4)},
+		{Field: storage.Field("score")},
+	} {
+		assert.ErrorIs(t, f.Validate(), storage.ErrInvalidFilter, "%+v", f)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	joke := models.NewJoke("a", "Title", "Ça va", 3)
+	empty := models.NewJoke("b", "Title", "", 0)
+
+	tests := []struct {
+		name   string
+		filter storage.Filter
+		joke   models.Joke
+		want   bool
+	}{
+		{"empty filter", storage.Filter{}, joke, true},
+		{"field is ignored", storage.Filter{Field: storage.FieldTitle}, joke, true},
+		{"min score", storage.Filter{MinScore: intPtr(3)}, joke, true},
+		{"below min score", storage.Filter{MinScore: intPtr(4)}, joke, false},
+		{"above max score", storage.Filter{MaxScore: intPtr(2)}, joke, false},
+		{"length in characters", storage.Filter{MinBodyLength: intPtr(5), MaxBodyLength: intPtr(5)}, joke, true},
+		{"too short", storage.Filter{MinBodyLength: intPtr(6)}, joke, false},
+		{"too long", storage.Filter{MaxBodyLength: intPtr(4)}, joke, false},
+		{"has body", storage.Filter{HasBody: boolPtr(true)}, joke, true},
+		{"has no body", storage.Filter{HasBody: boolPtr(false)}, joke, false},
+		{"empty body", storage.Filter{HasBody: boolPtr(false), MaxBodyLength: intPtr(0)}, empty, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.filter.Match(tt.joke), tt.name)
+	}
+}
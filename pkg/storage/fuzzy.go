@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// maxSuggestions limits the number of "did you mean" suggestions.
+const maxSuggestions = 3
+
+// Words splits text into lower case words, everything but letters and digits separates words.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// MaxEdits returns the number of typos tolerated in a word of the fuzzy search: none in words
+// of up to two letters, one in words of up to five letters and two in longer words.
+func MaxEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Distance returns the number of single rune insertions, deletions, substitutions and
+// transpositions of adjacent runes needed to turn a into b.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	// only the last three rows of the distance matrix are kept, transpositions look two rows back.
+	prev2, prev, cur := make([]int, len(t)+1), make([]int, len(t)+1), make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				cur[j] = minInt(cur[j], prev2[j-2]+1)
+			}
+		}
+
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(t)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
+
+// FuzzyTerms returns for every word of text the terms of the vocabulary within MaxEdits of it, closest first.
+// The result is empty, when the text has no words or any of them has no close term, as nothing can match then.
+func FuzzyTerms(text string, vocabulary []string) [][]string {
+	words := Words(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	groups := make([][]string, 0, len(words))
+	for _, word := range words {
+		terms := closeTerms(word, vocabulary, nil)
+		if len(terms) == 0 {
+			return nil
+		}
+		groups = append(groups, terms)
+	}
+	return groups
+}
+
+// closeTerms returns the terms within MaxEdits of word ordered by their distance, then by their
+// frequency, if frequencies are given, and alphabetically.
+func closeTerms(word string, vocabulary []string, frequency map[string]int) []string {
+	maxEdits := MaxEdits(word)
+	length := len([]rune(word))
+
+	distances := make(map[string]int)
+	for _, term := range vocabulary {
+		if diff := len([]rune(term)) - length; diff > maxEdits || -diff > maxEdits {
+			continue
+		}
+
+		if d := Distance(word, term); d <= maxEdits {
+			distances[term] = d
+		}
+	}
+
+	terms := make([]string, 0, len(distances))
+	for term := range distances {
+		terms = append(terms, term)
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		a, b := terms[i], terms[j]
+		if distances[a] != distances[b] {
+			return distances[a] < distances[b]
+		}
+		if frequency[a] != frequency[b] {
+			return frequency[a] > frequency[b]
+		}
+		return a < b
+	})
+	return terms
+}
+
+// Suggestions returns up to three corrections of the search text made of the words of the jokes,
+// which are usually the results of a fuzzy search for the text. Every word of the text is replaced
+// with the closest word of the jokes, ties go to the more frequent word. Words found in the jokes are kept
+// and the text itself is never suggested.
+func Suggestions(text string, jokes []models.Joke) []string {
+	words := Words(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	frequency := make(map[string]int)
+	for _, joke := range jokes {
+		for _, word := range append(Words(joke.Title), Words(joke.Body)...) {
+			frequency[word]++
+		}
+	}
+
+	vocabulary := make([]string, 0, len(frequency))
+	for word := range frequency {
+		vocabulary = append(vocabulary, word)
+	}
+
+	// words without a close word and words spelled right are kept.
+	candidates := make([][]string, len(words))
+	for i, word := range words {
+		if candidates[i] = closeTerms(word, vocabulary, frequency); len(candidates[i]) == 0 || candidates[i][0] == word {
+			candidates[i] = []string{word}
+		}
+	}
+
+	original := strings.Join(words, " ")
+	seen := map[string]bool{original: true}
+
+	var suggestions []string
+
+	// the k-th suggestion takes the k-th candidate of every word, which has that many.
+	for k := 0; len(suggestions) < maxSuggestions; k++ {
+		corrected := make([]string, len(words))
+		more := false
+
+		for i, terms := range candidates {
+			if k < len(terms) {
+				corrected[i] = terms[k]
+				more = true
+			} else {
+				corrected[i] = terms[0]
+			}
+		}
+
+		if !more {
+			break
+		}
+
+		if suggestion := strings.Join(corrected, " "); !seen[suggestion] {
+			seen[suggestion] = true
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
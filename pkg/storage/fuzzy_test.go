@@ -0,0 +1,60 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"horse", "horse", 0},
+		{"", "horse", 5},
+		{"horse", "hose", 1},
+		{"horse", "horsey", 1},
+		{"horse", "morse", 1},
+		{"horse", "hrose", 1},
+		{"kitten", "sitting", 3},
+		{"caña", "cana", 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, storage.Distance(tt.a, tt.b), "%s, %s", tt.a, tt.b)
+		assert.Equal(t, tt.want, storage.Distance(tt.b, tt.a), "%s, %s", tt.b, tt.a)
+	}
+}
+
+func TestMaxEdits(t *testing.T) {
+	assert.Equal(t, 0, storage.MaxEdits("ox"))
+	assert.Equal(t, 1, storage.MaxEdits("horse"))
+	assert.Equal(t, 2, storage.MaxEdits("bartender"))
+}
+
+func TestFuzzyTerms(t *testing.T) {
+	vocabulary := []string{"horse", "horses", "house", "morse", "bar", "bartender"}
+
+	assert.Equal(t, [][]string{{"horse", "horses", "house", "morse"}, {"bar"}}, storage.FuzzyTerms("Horse BAR", vocabulary))
+	assert.Equal(t, [][]string{{"horse"}}, storage.FuzzyTerms("hrose", vocabulary))
+	assert.Empty(t, storage.FuzzyTerms("horse penguin", vocabulary), "every word has to match")
+	assert.Empty(t, storage.FuzzyTerms("?!", vocabulary))
+}
+
+func TestSuggestions(t *testing.T) {
+	jokes := []models.Joke{
+		models.NewJoke("a", "Hockey night", "A horse plays hockey", 0),
+		models.NewJoke("b", "House", "The house of the horse is a house", 0),
+	}
+
+	assert.Equal(t, []string{"hockey"}, storage.Suggestions("hokey", jokes))
+	assert.Equal(t, []string{"horse night"}, storage.Suggestions("hrose nigth", jokes))
+	assert.Equal(t, []string{"house", "horse"}, storage.Suggestions("hose", jokes), "more frequent words come first")
+	assert.Equal(t, []string{"horse night"}, storage.Suggestions("horse nigth", jokes), "words found in the jokes are kept")
+	assert.Empty(t, storage.Suggestions("horse", jokes), "the text itself is not suggested")
+	assert.Empty(t, storage.Suggestions("", jokes))
+}
@@ -0,0 +1,16 @@
+package mongodb
+
+import (
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+func init() {
+	storage.Register("mongo", func(cfg config.Config) (storage.Storage, error) {
+		if err := cfg.Require("DB_URI", "DB_NAME", "JOKES_COLLECTION"); err != nil {
+			return nil, err
+		}
+
+		return NewDatabase(cfg.DbURI, cfg.DbName, cfg.JokesCollection)
+	})
+}
@@ -2,6 +2,9 @@ package mongodb
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/DanilLagunov/jokes-api/pkg/models"
@@ -14,8 +17,26 @@ import (
 
 // Database struct.
 type Database struct {
-	client          *mongo.Client
-	jokesCollection *mongo.Collection
+	client             *mongo.Client
+	jokesCollection    *mongo.Collection
+	countersCollection *mongo.Collection
+	vocabulary         storage.Vocabulary
+}
+
+// document is the stored form of a joke. Seq orders the jokes by insertion, as the ids do not,
+// and Rand, a random number below storage.RandModulus, is the base of the random orders.
+type document struct {
+	models.Joke `bson:",inline"`
+	Seq         int64 `bson:"seq"`
+	Rand        int64 `bson:"rand"`
+}
+
+// joke returns the joke of the document at its position.
+func (doc document) joke() models.Joke {
+	joke := doc.Joke
+	joke.Position = doc.Seq
+
+	return joke
 }
 
 // NewDatabase creating a new Database object.
@@ -27,139 +48,540 @@ func NewDatabase(uri, dbName, jokesCollectionName string) (*Database, error) {
 
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
 	db.client = client
+	if err != nil {
+		return &db, err
+	}
+
 	collection := client.Database(dbName).Collection(jokesCollectionName)
 	db.jokesCollection = collection
-	return &db, err
-}
+	db.countersCollection = client.Database(dbName).Collection("counters")
+
+	// the text index backs the text search mode, creating an existing index is a no-op.
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}},
+		Options: options.Index().
+			SetName("jokes_text").
+			SetWeights(bson.D{{Key: "title", Value: 2}, {Key: "body", Value: 1}}).
+			SetDefaultLanguage("none"),
+	})
+	if err != nil {
+		return &db, fmt.Errorf("creating text index error: %w", err)
+	}
 
-// GetJokes method returns a number of jokes given by skip and limit parameters and total amount of jokes.
-func (d *Database) GetJokes(ctx context.Context, skip, limit int) ([]models.Joke, int, error) {
-	amount, err := d.jokesCollection.CountDocuments(ctx, bson.M{})
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "seq", Value: 1}},
+		Options: options.Index().SetName("jokes_seq"),
+	})
 	if err != nil {
-		return []models.Joke{}, int(amount), err
+		return &db, fmt.Errorf("creating seq index error: %w", err)
 	}
 
-	options := options.Find()
-	options.SetSkip(int64(skip))
-	options.SetLimit(int64(limit))
+	if err := db.numberJokes(ctx); err != nil {
+		return &db, err
+	}
 
-	cur, err := d.jokesCollection.Find(ctx, bson.M{}, options)
+	// jokes stored before the random orders were based on Rand get their random numbers from the server.
+	_, err = collection.UpdateMany(ctx, bson.M{"rand": bson.M{"$exists": false}}, bson.A{
+		bson.M{"$set": bson.M{"rand": bson.M{"$toLong": bson.M{"$floor": bson.M{"$multiply": bson.A{bson.M{"$rand": bson.M{}}, storage.RandModulus}}}}}},
+	})
 	if err != nil {
-		return []models.Joke{}, int(amount), err
+		return &db, fmt.Errorf("setting random numbers error: %w", err)
+	}
+
+	return &db, nil
+}
+
+// numberJokes gives the jokes stored without seq the next numbers in their natural order,
+// which is the order they were inserted in.
+func (d *Database) numberJokes(ctx context.Context) error {
+	missing := bson.M{"seq": bson.M{"$exists": false}}
+
+	n, err := d.jokesCollection.CountDocuments(ctx, missing)
+	if err != nil || n == 0 {
+		return err
+	}
+
+	last, err := d.nextSeq(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	cur, err := d.jokesCollection.Find(ctx, missing,
+		options.Find().SetSort(bson.D{{Key: "$natural", Value: 1}}).SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return fmt.Errorf("numbering jokes error: %w", err)
 	}
 	defer cur.Close(ctx)
 
-	result := []models.Joke{}
+	var jokes []struct {
+		ID string `bson:"_id"`
+	}
 
-	if err := cur.All(ctx, &result); err != nil {
-		return result, int(amount), err
+	if err := cur.All(ctx, &jokes); err != nil {
+		return fmt.Errorf("numbering jokes error: %w", err)
 	}
 
-	return result, int(amount), nil
+	updates := make([]mongo.WriteModel, 0, len(jokes))
+	for i, joke := range jokes {
+		// another process numbering the jokes at the same time keeps the numbers it gave first.
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": joke.ID, "seq": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"seq": last - n + 1 + int64(i)}}))
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if _, err := d.jokesCollection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("numbering jokes error: %w", err)
+	}
+
+	return nil
+}
+
+// nextSeq reserves n numbers of the jokes and returns the last of them.
+func (d *Database) nextSeq(ctx context.Context, n int64) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	err := d.countersCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": d.jokesCollection.Name()},
+		bson.M{"$inc": bson.M{"seq": n}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("generating seq error: %w", err)
+	}
+
+	return counter.Seq, nil
+}
+
+// GetJokes method returns a number of jokes given by skip and limit parameters and total amount of jokes.
+// Jokes are ordered by their stored seq, which numbers them in insertion order.
+func (d *Database) GetJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, filter, storage.SortCreated)
 }
 
 // AddJoke method creating new joke.
 func (d *Database) AddJoke(ctx context.Context, title, body string, score int) (models.Joke, error) {
+	defer d.vocabulary.Invalidate()
+
 	id := primitive.NewObjectID().Hex()
-	joke := models.NewJoke(id, title, body, score)
 
-	_, err := d.jokesCollection.InsertOne(ctx, joke)
+	seq, err := d.nextSeq(ctx, 1)
+	if err != nil {
+		return models.Joke{}, err
+	}
 
-	return joke, err
+	doc := document{Joke: models.NewJoke(id, title, body, score), Seq: seq, Rand: storage.NewRand()}
+	if _, err := d.jokesCollection.InsertOne(ctx, doc); err != nil {
+		return models.Joke{}, err
+	}
+
+	return doc.joke(), nil
 }
 
 // GetJokesByText returns a number of jokes, which contain the desired text, given by skip and limit parameters and total amount of found jokes.
-func (d *Database) GetJokesByText(ctx context.Context, skip, limit int, text string) ([]models.Joke, int, error) {
-	filter := bson.M{"$or": []interface{}{
-		bson.M{"body": primitive.Regex{Pattern: text, Options: "i"}},
-		bson.M{"title": primitive.Regex{Pattern: text, Options: "i"}},
-	}}
+// In the plain mode every word of the text is matched as a case-insensitive prefix of a word in the title or the body and
+// in the regex mode the text is a pattern checked with storage.CompilePattern. Jokes are ranked by the number of matches in their title
+// and body. In the fuzzy mode every word matches whole words with a few typos, see storage.MaxEdits, and jokes are ranked the same way.
+// The text mode uses the text index, matches whole words and ranks jokes by the text score.
+func (d *Database) GetJokesByText(ctx context.Context, skip, limit int, text string, mode storage.SearchMode, filter storage.Filter) ([]models.Joke, int, error) {
+	conditions := filterConditions(filter)
+
+	switch mode {
+	case storage.SearchPlain:
+		words := storage.Words(text)
+		if len(words) == 0 {
+			return []models.Joke{}, 0, nil
+		}
+
+		for i, word := range words {
+			words[i] = prefixPattern(word)
+			conditions = append(conditions, patternFilter(words[i], filter.Field))
+		}
+
+		return d.getJokesByPattern(ctx, skip, limit, matchAll(conditions), strings.Join(words, "|"), filter)
+	case storage.SearchRegex:
+		if _, err := storage.CompilePattern(text); err != nil {
+			return []models.Joke{}, 0, err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, storage.RegexTimeout)
+		defer cancel()
+
+		conditions = append(conditions, patternFilter(text, filter.Field))
+
+		return d.getJokesByPattern(ctx, skip, limit, matchAll(conditions), text, filter)
+	case storage.SearchText:
+		// the text index covers both fields, a field filter additionally matches the words in the field.
+		if filter.Field != storage.FieldAny {
+			for _, word := range strings.Fields(text) {
+				conditions = append(conditions, patternFilter(`\b`+regexp.QuoteMeta(word)+`\b`, filter.Field))
+			}
+		}
+
+		conditions = append(bson.A{bson.M{"$text": bson.M{"$search": text}}}, conditions...)
+
+		return d.getJokesByTextIndex(ctx, skip, limit, matchAll(conditions), filter)
+	case storage.SearchFuzzy:
+		vocabulary, err := d.vocabulary.Terms(ctx, d.words)
+		if err != nil {
+			return []models.Joke{}, 0, err
+		}
+
+		groups := storage.FuzzyTerms(text, vocabulary)
+		if len(groups) == 0 {
+			return []models.Joke{}, 0, nil
+		}
+
+		alternatives := []string{}
+		for _, terms := range groups {
+			for i, term := range terms {
+				terms[i] = regexp.QuoteMeta(term)
+			}
+			alternatives = append(alternatives, terms...)
+			conditions = append(conditions, patternFilter(wordsPattern(terms), filter.Field))
+		}
+
+		return d.getJokesByPattern(ctx, skip, limit, matchAll(conditions), wordsPattern(alternatives), filter)
+	default:
+		return []models.Joke{}, 0, fmt.Errorf("%w: %q", storage.ErrUnknownSearchMode, mode)
+	}
+}
+
+// patternFilter matches the jokes whose title or body, as given by field, match pattern case-insensitively.
+func patternFilter(pattern string, field storage.Field) bson.M {
+	alternatives := bson.A{}
+	for _, name := range fieldNames(field) {
+		alternatives = append(alternatives, bson.M{name: primitive.Regex{Pattern: pattern, Options: "i"}})
+	}
+	return bson.M{"$or": alternatives}
+}
+
+// fieldNames returns the names of the searched fields.
+func fieldNames(field storage.Field) []string {
+	if field == storage.FieldAny {
+		return []string{"title", "body"}
+	}
+	return []string{string(field)}
+}
+
+// filterConditions returns the conditions of the filter.
+func filterConditions(filter storage.Filter) bson.A {
+	conditions := bson.A{}
+
+	if filter.MinScore != nil {
+		conditions = append(conditions, bson.M{"score": bson.M{"$gte": *filter.MinScore}})
+	}
+	if filter.MaxScore != nil {
+		conditions = append(conditions, bson.M{"score": bson.M{"$lte": *filter.MaxScore}})
+	}
+
+	bodyLength := bson.M{"$strLenCP": "$body"}
+	if filter.MinBodyLength != nil {
+		conditions = append(conditions, bson.M{"$expr": bson.M{"$gte": bson.A{bodyLength, *filter.MinBodyLength}}})
+	}
+	if filter.MaxBodyLength != nil {
+		conditions = append(conditions, bson.M{"$expr": bson.M{"$lte": bson.A{bodyLength, *filter.MaxBodyLength}}})
+	}
+
+	if filter.HasBody != nil {
+		if *filter.HasBody {
+			conditions = append(conditions, bson.M{"body": bson.M{"$ne": ""}})
+		} else {
+			conditions = append(conditions, bson.M{"body": ""})
+		}
+	}
+
+	return conditions
+}
+
+// matchAll matches the jokes meeting all the conditions, no conditions match every joke.
+func matchAll(conditions bson.A) bson.M {
+	if len(conditions) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": conditions}
+}
+
+// prefixPattern matches the word as the prefix of a word. Unlike \b, which only knows ASCII letters,
+// the start of a word is found after anything but letters and digits of any script.
+func prefixPattern(word string) string {
+	return `(?:^|[^\p{L}\p{N}])` + regexp.QuoteMeta(word)
+}
+
+// wordsPattern matches any of the quoted words as a whole word.
+func wordsPattern(words []string) string {
+	return `\b(?:` + strings.Join(words, "|") + `)\b`
+}
 
-	amount, err := d.jokesCollection.CountDocuments(ctx, filter)
+// words returns the distinct lower case words of all jokes.
+func (d *Database) words(ctx context.Context) ([]string, error) {
+	cur, err := d.jokesCollection.Aggregate(ctx, []bson.M{
+		{"$project": bson.M{"words": bson.M{"$regexFindAll": bson.M{
+			"input": bson.M{"$toLower": bson.M{"$concat": bson.A{"$title", " ", "$body"}}},
+			"regex": `[\p{L}\p{N}]+`,
+		}}}},
+		{"$unwind": "$words"},
+		{"$group": bson.M{"_id": "$words.match"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var words []struct {
+		Word string `bson:"_id"`
+	}
+
+	if err := cur.All(ctx, &words); err != nil {
+		return nil, err
+	}
+
+	vocabulary := make([]string, 0, len(words))
+	for _, w := range words {
+		vocabulary = append(vocabulary, w.Word)
+	}
+	return vocabulary, nil
+}
+
+// getJokesByPattern returns the jokes matching the regular expressions of match ranked by the occurrences of pattern.
+// The server stops matching after storage.RegexTimeout, even when the deadline of the context is not sent along.
+func (d *Database) getJokesByPattern(ctx context.Context, skip, limit int, match bson.M, pattern string, filter storage.Filter) ([]models.Joke, int, error) {
+	amount, err := d.jokesCollection.CountDocuments(ctx, match, options.Count().SetMaxTime(storage.RegexTimeout))
 	if err != nil {
 		return []models.Joke{}, int(amount), err
 	}
 
-	options := options.Find()
-	options.SetSkip(int64(skip))
-	options.SetLimit(int64(limit))
+	if limit <= 0 {
+		return []models.Joke{}, int(amount), nil
+	}
+
+	occurrences := bson.A{}
+	for _, name := range fieldNames(filter.Field) {
+		occurrences = append(occurrences,
+			bson.M{"$size": bson.M{"$regexFindAll": bson.M{"input": "$" + name, "regex": pattern, "options": "i"}}})
+	}
+
+	pipeline := append([]bson.M{{"$match": match}}, searchSort(filter, []bson.M{
+		{"$addFields": bson.M{"relevance": bson.M{"$add": occurrences}}},
+		{"$sort": bson.D{{Key: "relevance", Value: -1}, {Key: "seq", Value: 1}}},
+	})...)
 
+	return d.aggregate(ctx, int(amount), append(pipeline, bson.M{"$skip": skip}, bson.M{"$limit": limit}),
+		options.Aggregate().SetMaxTime(storage.RegexTimeout))
+}
+
+// searchSort returns the stages sorting found jokes in the order of the filter, relevance is the ranking.
+func searchSort(filter storage.Filter, ranking []bson.M) []bson.M {
+	if filter.Sort == storage.SortDefault || filter.Sort == storage.SortRelevance {
+		return ranking
+	}
+	return sortStages(filter.Sort, filter.RandSeed)
+}
+
+func (d *Database) getJokesByTextIndex(ctx context.Context, skip, limit int, match bson.M, filter storage.Filter) ([]models.Joke, int, error) {
+	amount, err := d.jokesCollection.CountDocuments(ctx, match)
+	if err != nil {
+		return []models.Joke{}, int(amount), err
+	}
+
+	if limit <= 0 {
+		return []models.Joke{}, int(amount), nil
+	}
+
+	pipeline := append([]bson.M{{"$match": match}}, searchSort(filter, []bson.M{
+		{"$sort": bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "seq", Value: 1}}},
+	})...)
+
+	return d.aggregate(ctx, int(amount), append(pipeline, bson.M{"$skip": skip}, bson.M{"$limit": limit}))
+}
+
+func (d *Database) aggregate(ctx context.Context, amount int, pipeline []bson.M, opts ...*options.AggregateOptions) ([]models.Joke, int, error) {
 	result := []models.Joke{}
 
-	cur, err := d.jokesCollection.Find(ctx, filter, options)
+	cur, err := d.jokesCollection.Aggregate(ctx, pipeline, opts...)
 	if err != nil {
-		return result, int(amount), err
+		return result, amount, err
 	}
 	defer cur.Close(ctx)
 
-	if err := cur.All(ctx, &result); err != nil {
-		return result, int(amount), err
+	var docs []document
+	if err := cur.All(ctx, &docs); err != nil {
+		return result, amount, err
+	}
+
+	for _, doc := range docs {
+		result = append(result, doc.joke())
 	}
 
-	return result, int(amount), nil
+	return result, amount, nil
 }
 
 // GetJokeByID returns joke that has the same id.
 func (d *Database) GetJokeByID(ctx context.Context, id string) (models.Joke, error) {
 	filter := bson.M{"_id": id}
 
-	var joke models.Joke
+	var doc document
 
-	err := d.jokesCollection.FindOne(ctx, filter).Decode(&joke)
+	err := d.jokesCollection.FindOne(ctx, filter).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
-		return joke, storage.ErrJokeNotFound
+		return models.Joke{}, storage.ErrJokeNotFound
 	}
 
-	return joke, err
+	return doc.joke(), err
+}
+
+// GetRandomJokes returns number of random jokes given by skip and limit parameters and total amount of jokes.
+// The jokes are shuffled in the SortRandom order of randSeed, so the same seed pages through the same permutation.
+func (d *Database) GetRandomJokes(ctx context.Context, skip, limit int, randSeed int64) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, storage.Filter{Sort: storage.SortRandom, RandSeed: randSeed}, storage.SortRandom)
 }
 
-// GetRandomJokes returns number of random jokes given by limit parameter and total amount of jokes.
-func (d *Database) GetRandomJokes(ctx context.Context, limit int) ([]models.Joke, int, error) {
-	amount, err := d.jokesCollection.CountDocuments(ctx, bson.M{})
+// GetFunniestJokes returns number of sorted jokes given by skip and limit parameters and total amount of jokes.
+func (d *Database) GetFunniestJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, filter, storage.SortScoreDesc)
+}
+
+// list returns the page of the jokes passing the filter in its order, def is the order of the listing.
+func (d *Database) list(ctx context.Context, skip, limit int, filter storage.Filter, def storage.Sort) ([]models.Joke, int, error) {
+	order, err := storage.ListingSort(filter, def)
 	if err != nil {
-		return []models.Joke{}, int(amount), err
+		return []models.Joke{}, 0, err
 	}
 
-	pipeline := []bson.M{{"$sample": bson.M{"size": limit}}}
+	conditions := filterConditions(filter)
 
-	cur, err := d.jokesCollection.Aggregate(ctx, pipeline)
+	amount, err := d.jokesCollection.CountDocuments(ctx, matchAll(conditions))
 	if err != nil {
 		return []models.Joke{}, int(amount), err
 	}
 
-	defer cur.Close(ctx)
+	if filter.After != nil {
+		conditions = append(conditions, cursorCondition(order, *filter.After))
+	}
 
-	result := []models.Joke{}
-	if err := cur.All(ctx, &result); err != nil {
-		return result, int(amount), err
+	if limit <= 0 {
+		return []models.Joke{}, int(amount), nil
 	}
 
-	return result, int(amount), nil
+	pipeline := append([]bson.M{{"$match": matchAll(conditions)}}, sortStages(order, filter.RandSeed)...)
+
+	return d.aggregate(ctx, int(amount), append(pipeline, bson.M{"$skip": skip}, bson.M{"$limit": limit}))
 }
 
-// GetFunniestJokes returns number of sorted jokes given by skip and limit parameters and total amount of jokes.
-func (d *Database) GetFunniestJokes(ctx context.Context, skip, limit int) ([]models.Joke, int, error) {
-	amount, err := d.jokesCollection.CountDocuments(ctx, bson.M{})
-	if err != nil {
-		return []models.Joke{}, int(amount), err
+// sortStages returns the stages sorting the jokes in the order, ties are broken by seq, which keeps the insertion order.
+func sortStages(order storage.Sort, seed int64) []bson.M {
+	switch order {
+	case storage.SortScore:
+		return []bson.M{{"$sort": bson.D{{Key: "score", Value: 1}, {Key: "seq", Value: 1}}}}
+	case storage.SortScoreDesc:
+		return []bson.M{{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "seq", Value: 1}}}}
+	case storage.SortTitle:
+		return []bson.M{
+			{"$addFields": bson.M{"order": bson.M{"$toLower": "$title"}}},
+			{"$sort": bson.D{{Key: "order", Value: 1}, {Key: "seq", Value: 1}}},
+		}
+	case storage.SortRandom:
+		// every seed makes its own permutation of the random numbers.
+		a, b := storage.ShuffleFactors(seed)
+
+		return []bson.M{
+			{"$addFields": bson.M{"order": bson.M{"$mod": bson.A{
+				bson.M{"$add": bson.A{bson.M{"$multiply": bson.A{"$rand", a}}, b}},
+				int64(storage.RandModulus),
+			}}}},
+			{"$sort": bson.D{{Key: "order", Value: 1}, {Key: "seq", Value: 1}}},
+		}
+	default:
+		return []bson.M{{"$sort": bson.D{{Key: "seq", Value: 1}}}}
 	}
+}
 
-	findOptions := options.Find()
-	findOptions.SetSort(bson.M{"score": -1})
-	findOptions.SetSkip(int64(skip))
-	findOptions.SetLimit(int64(limit))
+// cursorCondition matches the jokes following the cursor in the order.
+func cursorCondition(order storage.Sort, after storage.Cursor) bson.M {
+	switch order {
+	case storage.SortScore:
+		return bson.M{"$or": bson.A{
+			bson.M{"score": bson.M{"$gt": after.Score}},
+			bson.M{"score": after.Score, "seq": bson.M{"$gt": after.Position}},
+		}}
+	case storage.SortScoreDesc:
+		return bson.M{"$or": bson.A{
+			bson.M{"score": bson.M{"$lt": after.Score}},
+			bson.M{"score": after.Score, "seq": bson.M{"$gt": after.Position}},
+		}}
+	default:
+		return bson.M{"seq": bson.M{"$gt": after.Position}}
+	}
+}
 
-	cur, err := d.jokesCollection.Find(ctx, bson.M{}, findOptions)
+// UpdateJoke replaces title and body of the joke that has the same id and returns the updated joke.
+func (d *Database) UpdateJoke(ctx context.Context, id, title, body string) (models.Joke, error) {
+	defer d.vocabulary.Invalidate()
+
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"title": title, "body": body}}
+
+	updateOptions := options.FindOneAndUpdate()
+	updateOptions.SetReturnDocument(options.After)
+
+	var doc document
+
+	err := d.jokesCollection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return models.Joke{}, storage.ErrJokeNotFound
+	}
+
+	return doc.joke(), err
+}
+
+// DeleteJoke removes joke that has the same id.
+func (d *Database) DeleteJoke(ctx context.Context, id string) error {
+	defer d.vocabulary.Invalidate()
+
+	res, err := d.jokesCollection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
-		return nil, int(amount), err
+		return err
 	}
-	defer cur.Close(ctx)
 
-	result := []models.Joke{}
+	if res.DeletedCount == 0 {
+		return storage.ErrJokeNotFound
+	}
+
+	return nil
+}
+
+// IncrementScore atomically adds delta to the score of the joke that has the same id and returns the updated joke.
+func (d *Database) IncrementScore(ctx context.Context, id string, delta int) (models.Joke, error) {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$inc": bson.M{"score": delta}}
 
-	if err := cur.All(ctx, &result); err != nil {
-		return result, int(amount), err
+	updateOptions := options.FindOneAndUpdate()
+	updateOptions.SetReturnDocument(options.After)
+
+	var doc document
+
+	err := d.jokesCollection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return models.Joke{}, storage.ErrJokeNotFound
 	}
 
-	return result, int(amount), nil
+	return doc.joke(), err
+}
+
+// Truncate removes all jokes and restarts their numbering, it is meant for tests and data imports.
+func (d *Database) Truncate(ctx context.Context) error {
+	defer d.vocabulary.Invalidate()
+
+	if _, err := d.jokesCollection.DeleteMany(ctx, bson.M{}); err != nil {
+		return err
+	}
+
+	_, err := d.countersCollection.DeleteOne(ctx, bson.M{"_id": d.jokesCollection.Name()})
+
+	return err
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/DanilLagunov/jokes-api/pkg/models"
 	"github.com/DanilLagunov/jokes-api/pkg/storage"
 	"github.com/DanilLagunov/jokes-api/pkg/storage/mongodb"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/storagetest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,7 +24,10 @@ const (
 	JokesCollectionName string = "jokes"
 )
 
-var jokeID string
+var (
+	jokeID       string
+	jokePosition int64
+)
 
 func TestMain(m *testing.M) {
 	prepareDBForTests()
@@ -47,6 +51,7 @@ func prepareDBForTests() {
 		log.Fatal()
 	}
 	jokeID = joke.ID
+	jokePosition = joke.Position
 }
 
 func TestGetJokes(t *testing.T) {
@@ -58,19 +63,19 @@ func TestGetJokes(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
-	result, amount, err := db.GetJokes(ctx, 0, 3)
+	result, amount, err := db.GetJokes(ctx, 0, 3, storage.Filter{})
 	require.NoError(t, err)
 
 	assert.EqualValues(t, "First joke", result[0].Title)
 	assert.EqualValues(t, 3, amount)
 
-	result, amount, err = db.GetJokes(ctx, 1, 4)
+	result, amount, err = db.GetJokes(ctx, 1, 4, storage.Filter{})
 	require.NoError(t, err)
 
 	assert.EqualValues(t, "Second joke", result[0].Title)
 	assert.EqualValues(t, 3, amount)
 
-	result, amount, err = db.GetJokes(ctx, 4, 4)
+	result, amount, err = db.GetJokes(ctx, 4, 4, storage.Filter{})
 	require.NoError(t, err)
 
 	assert.EqualValues(t, []models.Joke{}, result)
@@ -109,7 +114,7 @@ func TestGetJokeByText(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		result, amount, err := db.GetJokesByText(ctx, 0, 3, tc.Text)
+		result, amount, err := db.GetJokesByText(ctx, 0, 3, tc.Text, storage.SearchPlain, storage.Filter{})
 		if tc.Valid {
 			require.NoError(t, err)
 		}
@@ -119,7 +124,7 @@ func TestGetJokeByText(t *testing.T) {
 		}
 	}
 
-	result, _, err := db.GetJokesByText(ctx, 4, 1, "first")
+	result, _, err := db.GetJokesByText(ctx, 4, 1, "first", storage.SearchPlain, storage.Filter{})
 	require.NoError(t, err)
 
 	assert.EqualValues(t, []models.Joke{}, result)
@@ -141,7 +146,7 @@ func TestGetJokeByID(t *testing.T) {
 	}{
 		{
 			ID:       jokeID,
-			Expected: models.Joke{ID: jokeID, Title: "Third joke", Body: "Funny", Score: 15},
+			Expected: models.Joke{ID: jokeID, Title: "Third joke", Body: "Funny", Score: 15, Position: jokePosition},
 			Valid:    true,
 		},
 		{
@@ -173,7 +178,7 @@ func TestGetFunniestJokes(t *testing.T) {
 
 	expected := []int{35, 15, 3}
 
-	result, amount, err := db.GetFunniestJokes(ctx, 0, 3)
+	result, amount, err := db.GetFunniestJokes(ctx, 0, 3, storage.Filter{})
 	require.NoError(t, err)
 
 	for i := 0; i < amount; i++ {
@@ -181,7 +186,7 @@ func TestGetFunniestJokes(t *testing.T) {
 	}
 	assert.EqualValues(t, 3, amount)
 
-	result, _, err = db.GetFunniestJokes(ctx, 4, 4)
+	result, _, err = db.GetFunniestJokes(ctx, 4, 4, storage.Filter{})
 	require.NoError(t, err)
 
 	assert.EqualValues(t, []models.Joke{}, result)
@@ -202,7 +207,7 @@ func TestGetRandomJokes(t *testing.T) {
 		{ID: "6150ed6dc471125ddd1a0912", Title: "Third joke", Body: "Funny", Score: 15},
 	}
 
-	random, size, err := db.GetRandomJokes(ctx, 3)
+	random, size, err := db.GetRandomJokes(ctx, 0, 3, 42)
 	require.NoError(t, err)
 
 	pass := false
@@ -236,3 +241,90 @@ func TestAddJoke(t *testing.T) {
 	assert.EqualValues(t, expTitle, result.Title)
 	assert.EqualValues(t, expBody, result.Body)
 }
+
+func TestUpdateJoke(t *testing.T) {
+	db, err := mongodb.NewDatabase(URI, DBName, JokesCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	joke, err := db.AddJoke(ctx, "Joke to update", "Old", 7)
+	require.NoError(t, err)
+
+	result, err := db.UpdateJoke(ctx, joke.ID, "Updated joke", "New")
+	require.NoError(t, err)
+	assert.EqualValues(t, models.Joke{ID: joke.ID, Title: "Updated joke", Body: "New", Score: 7, Position: joke.Position}, result)
+
+	_, err = db.UpdateJoke(ctx, "123456", "Updated joke", "New")
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+
+	require.NoError(t, db.DeleteJoke(ctx, joke.ID))
+}
+
+func TestDeleteJoke(t *testing.T) {
+	db, err := mongodb.NewDatabase(URI, DBName, JokesCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	joke, err := db.AddJoke(ctx, "Joke to delete", "Spam", 0)
+	require.NoError(t, err)
+
+	require.NoError(t, db.DeleteJoke(ctx, joke.ID))
+
+	_, err = db.GetJokeByID(ctx, joke.ID)
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+
+	err = db.DeleteJoke(ctx, joke.ID)
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+}
+
+func TestIncrementScore(t *testing.T) {
+	db, err := mongodb.NewDatabase(URI, DBName, JokesCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	joke, err := db.AddJoke(ctx, "Joke to vote", "Vote", 10)
+	require.NoError(t, err)
+
+	result, err := db.IncrementScore(ctx, joke.ID, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 11, result.Score)
+
+	result, err = db.IncrementScore(ctx, joke.ID, -3)
+	require.NoError(t, err)
+	assert.EqualValues(t, 8, result.Score)
+
+	_, err = db.IncrementScore(ctx, "123456", 1)
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+
+	require.NoError(t, db.DeleteJoke(ctx, joke.ID))
+}
+
+func TestConformance(t *testing.T) {
+	db, err := mongodb.NewDatabase(URI, DBName, "jokes_conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		if err := db.Truncate(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		return db
+	})
+}
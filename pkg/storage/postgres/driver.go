@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+func init() {
+	storage.Register("postgres", func(cfg config.Config) (storage.Storage, error) {
+		if err := cfg.Require("POSTGRES_DSN"); err != nil {
+			return nil, err
+		}
+
+		return NewDatabase(cfg.PostgresDSN, PoolConfig{
+			MaxOpenConns:    cfg.PostgresMaxOpenConns,
+			MaxIdleConns:    cfg.PostgresMaxIdleConns,
+			ConnMaxLifetime: cfg.PostgresConnLifetime,
+			ConnMaxIdleTime: cfg.PostgresConnIdleTime,
+		})
+	})
+}
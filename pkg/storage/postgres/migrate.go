@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationsLockID is the key of the advisory lock, that keeps concurrently starting replicas
+// from applying the same migration twice.
+const migrationsLockID = 7146825302
+
+// migrate applies the embedded migrations, which have not been applied yet, in the order of their names.
+func migrate(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return fmt.Errorf("listing migrations error: %w", err)
+	}
+
+	sort.Strings(names)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting error: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID); err != nil {
+		return fmt.Errorf("locking migrations error: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockID) //nolint:errcheck
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating migrations table error: %w", err)
+	}
+
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+
+		if err := applyMigration(ctx, conn, name, version); err != nil {
+			return fmt.Errorf("migration %s error: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, name, version string) error {
+	var applied bool
+
+	err := conn.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied)
+	if err != nil || applied {
+		return err
+	}
+
+	statements, err := migrations.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, string(statements)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
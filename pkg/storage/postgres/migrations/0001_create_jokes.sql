@@ -0,0 +1,14 @@
+CREATE TABLE jokes (
+    seq    BIGSERIAL NOT NULL UNIQUE,
+    id     TEXT PRIMARY KEY,
+    title  TEXT NOT NULL,
+    body   TEXT NOT NULL,
+    score  INTEGER NOT NULL DEFAULT 0,
+    search TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', body), 'B')
+    ) STORED
+);
+
+CREATE INDEX jokes_score_idx ON jokes (score DESC, seq);
+
+CREATE INDEX jokes_search_idx ON jokes USING GIN (search);
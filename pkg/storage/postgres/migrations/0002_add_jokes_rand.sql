@@ -0,0 +1,5 @@
+ALTER TABLE jokes ADD COLUMN rand BIGINT;
+
+UPDATE jokes SET rand = floor(random() * 2147483647);
+
+ALTER TABLE jokes ALTER COLUMN rand SET NOT NULL;
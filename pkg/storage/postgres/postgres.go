@@ -0,0 +1,466 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+
+	// registers the "pgx" driver.
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const jokeColumns = "id, title, body, score, seq"
+
+// maxIDAttempts limits the number of attempts to generate a unique id for a new joke.
+const maxIDAttempts = 10
+
+// PoolConfig holds the settings of the connection pool, zero values keep the database/sql defaults.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Database struct.
+type Database struct {
+	db         *sql.DB
+	vocabulary storage.Vocabulary
+}
+
+// NewDatabase connects to the PostgreSQL database given by dsn and applies the migrations.
+func NewDatabase(dsn string, pool PoolConfig) (*Database, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database error: %w", err)
+	}
+
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return &Database{db: db}, nil
+}
+
+// Close closes the database.
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// GetJokes method returns a number of jokes given by skip and limit parameters and total amount of jokes.
+func (d *Database) GetJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, filter, storage.SortCreated)
+}
+
+// AddJoke method creating new joke.
+func (d *Database) AddJoke(ctx context.Context, title, body string, score int) (models.Joke, error) {
+	defer d.vocabulary.Invalidate()
+
+	for i := 0; i < maxIDAttempts; i++ {
+		id, err := models.GenerateID()
+		if err != nil {
+			return models.Joke{}, fmt.Errorf("ID generating error: %w", err)
+		}
+
+		joke := models.NewJoke(id, title, body, score)
+
+		// a taken id inserts no row and returns no seq.
+		err = d.db.QueryRowContext(ctx,
+			"INSERT INTO jokes (id, title, body, score, rand) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO NOTHING RETURNING seq",
+			id, title, body, score, storage.NewRand()).Scan(&joke.Position)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return models.Joke{}, fmt.Errorf("inserting joke error: %w", err)
+		}
+
+		return joke, nil
+	}
+
+	return models.Joke{}, errors.New("ID generating error: no unique id found")
+}
+
+// GetJokesByText returns a number of jokes, which contain the desired words, given by skip and limit parameters
+// and total amount of found jokes. In the plain and text modes every word of the text is matched as a case-insensitive
+// prefix and jokes are ranked by ts_rank, which weighs matches in the title higher than matches in the body.
+// In the regex mode the text is a pattern checked with storage.CompilePattern and jokes are ranked by the number of matches.
+// In the fuzzy mode every word matches the indexed lexemes with a few typos, see storage.MaxEdits, and jokes are ranked by ts_rank.
+func (d *Database) GetJokesByText(ctx context.Context, skip, limit int, text string, mode storage.SearchMode, filter storage.Filter) ([]models.Joke, int, error) {
+	var query string
+
+	switch mode {
+	case storage.SearchPlain, storage.SearchText:
+		query = tsQuery(text)
+	case storage.SearchRegex:
+		return d.getJokesByPattern(ctx, skip, limit, text, filter)
+	case storage.SearchFuzzy:
+		var err error
+		if query, err = d.fuzzyQuery(ctx, text); err != nil {
+			return []models.Joke{}, 0, err
+		}
+	default:
+		return []models.Joke{}, 0, fmt.Errorf("%w: %q", storage.ErrUnknownSearchMode, mode)
+	}
+
+	if query == "" {
+		return []models.Joke{}, 0, nil
+	}
+
+	// a column filter searches the column instead of the weighted document.
+	document := "search"
+	if filter.Field == storage.FieldTitle || filter.Field == storage.FieldBody {
+		document = "to_tsvector('simple', " + string(filter.Field) + ")"
+	}
+
+	conditions, args := filterConditions(2, filter)
+	conditions = append([]string{document + " @@ to_tsquery('simple', $1)"}, conditions...)
+	args = append([]interface{}{query}, args...)
+
+	amount, err := d.count(ctx, "SELECT COUNT(*) FROM jokes"+where(conditions), args...)
+	if err != nil {
+		return []models.Joke{}, amount, err
+	}
+
+	order := "ts_rank(" + document + ", to_tsquery('simple', $1)) DESC, seq"
+	if filter.Sort != storage.SortDefault && filter.Sort != storage.SortRelevance {
+		order = orderBy(filter.Sort, filter.RandSeed)
+	}
+
+	n := len(args) + 1
+	result, err := d.query(ctx, "SELECT "+jokeColumns+" FROM jokes"+where(conditions)+
+		" ORDER BY "+order+fmt.Sprintf(" LIMIT $%d OFFSET $%d", n, n+1), append(args, limit, skip)...)
+
+	return result, amount, err
+}
+
+// fuzzyQuery returns the tsquery matching the lexemes close to every word of the text or
+// an empty string, when some word has no close lexeme.
+func (d *Database) fuzzyQuery(ctx context.Context, text string) (string, error) {
+	vocabulary, err := d.vocabulary.Terms(ctx, d.lexemes)
+	if err != nil {
+		return "", err
+	}
+
+	groups := storage.FuzzyTerms(text, vocabulary)
+
+	queries := make([]string, 0, len(groups))
+	for _, terms := range groups {
+		for i, term := range terms {
+			terms[i] = "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(term) + "'"
+		}
+		queries = append(queries, "("+strings.Join(terms, " | ")+")")
+	}
+
+	return strings.Join(queries, " & "), nil
+}
+
+// lexemes returns the indexed lexemes of all jokes.
+func (d *Database) lexemes(ctx context.Context) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT word FROM ts_stat('SELECT search FROM jokes')")
+	if err != nil {
+		return nil, fmt.Errorf("querying lexemes error: %w", err)
+	}
+	defer rows.Close()
+
+	var vocabulary []string
+
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, fmt.Errorf("scanning lexeme error: %w", err)
+		}
+		vocabulary = append(vocabulary, word)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying lexemes error: %w", err)
+	}
+
+	return vocabulary, nil
+}
+
+func (d *Database) getJokesByPattern(ctx context.Context, skip, limit int, pattern string, filter storage.Filter) ([]models.Joke, int, error) {
+	if _, err := storage.CompilePattern(pattern); err != nil {
+		return []models.Joke{}, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storage.RegexTimeout)
+	defer cancel()
+
+	var matches, counts []string
+	for _, column := range []string{"title", "body"} {
+		if filter.Field == storage.FieldAny || string(filter.Field) == column {
+			matches = append(matches, column+" ~* $1")
+			counts = append(counts, "(SELECT COUNT(*) FROM regexp_matches("+column+", $1, 'gi'))")
+		}
+	}
+
+	conditions, args := filterConditions(2, filter)
+	conditions = append([]string{"(" + strings.Join(matches, " OR ") + ")"}, conditions...)
+	args = append([]interface{}{pattern}, args...)
+
+	amount, err := d.count(ctx, "SELECT COUNT(*) FROM jokes"+where(conditions), args...)
+	if err != nil {
+		return []models.Joke{}, amount, err
+	}
+
+	order := strings.Join(counts, " + ") + " DESC, seq"
+	if filter.Sort != storage.SortDefault && filter.Sort != storage.SortRelevance {
+		order = orderBy(filter.Sort, filter.RandSeed)
+	}
+
+	n := len(args) + 1
+	result, err := d.query(ctx, "SELECT "+jokeColumns+" FROM jokes"+where(conditions)+
+		" ORDER BY "+order+fmt.Sprintf(" LIMIT $%d OFFSET $%d", n, n+1), append(args, limit, skip)...)
+
+	return result, amount, err
+}
+
+// GetJokeByID returns joke that has the same id.
+func (d *Database) GetJokeByID(ctx context.Context, id string) (models.Joke, error) {
+	return d.queryOne(ctx, "SELECT "+jokeColumns+" FROM jokes WHERE id = $1", id)
+}
+
+// GetRandomJokes returns number of random jokes given by skip and limit parameters and total amount of jokes.
+// The jokes are shuffled in the SortRandom order of randSeed, so the same seed pages through the same permutation.
+func (d *Database) GetRandomJokes(ctx context.Context, skip, limit int, randSeed int64) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, storage.Filter{Sort: storage.SortRandom, RandSeed: randSeed}, storage.SortRandom)
+}
+
+// GetFunniestJokes returns number of sorted jokes given by skip and limit parameters and total amount of jokes.
+func (d *Database) GetFunniestJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, filter, storage.SortScoreDesc)
+}
+
+// list returns the page of the jokes passing the filter in its order, def is the order of the listing.
+func (d *Database) list(ctx context.Context, skip, limit int, filter storage.Filter, def storage.Sort) ([]models.Joke, int, error) {
+	order, err := storage.ListingSort(filter, def)
+	if err != nil {
+		return []models.Joke{}, 0, err
+	}
+
+	conditions, args := filterConditions(1, filter)
+
+	amount, err := d.count(ctx, "SELECT COUNT(*) FROM jokes"+where(conditions), args...)
+	if err != nil {
+		return []models.Joke{}, amount, err
+	}
+
+	if filter.After != nil {
+		condition, cursorArgs := cursorCondition(order, len(args)+1, *filter.After)
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	n := len(args) + 1
+	result, err := d.query(ctx, "SELECT "+jokeColumns+" FROM jokes"+where(conditions)+
+		" ORDER BY "+orderBy(order, filter.RandSeed)+fmt.Sprintf(" LIMIT $%d OFFSET $%d", n, n+1),
+		append(args, limit, skip)...)
+
+	return result, amount, err
+}
+
+// UpdateJoke replaces title and body of the joke that has the same id and returns the updated joke.
+func (d *Database) UpdateJoke(ctx context.Context, id, title, body string) (models.Joke, error) {
+	defer d.vocabulary.Invalidate()
+
+	return d.queryOne(ctx,
+		"UPDATE jokes SET title = $1, body = $2 WHERE id = $3 RETURNING "+jokeColumns, title, body, id)
+}
+
+// DeleteJoke removes joke that has the same id.
+func (d *Database) DeleteJoke(ctx context.Context, id string) error {
+	defer d.vocabulary.Invalidate()
+
+	res, err := d.db.ExecContext(ctx, "DELETE FROM jokes WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("deleting joke error: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting joke error: %w", err)
+	}
+
+	if deleted == 0 {
+		return storage.ErrJokeNotFound
+	}
+
+	return nil
+}
+
+// IncrementScore atomically adds delta to the score of the joke that has the same id and returns the updated joke.
+func (d *Database) IncrementScore(ctx context.Context, id string, delta int) (models.Joke, error) {
+	return d.queryOne(ctx,
+		"UPDATE jokes SET score = score + $1 WHERE id = $2 RETURNING "+jokeColumns, delta, id)
+}
+
+func (d *Database) count(ctx context.Context, query string, args ...interface{}) (int, error) {
+	var amount int
+
+	if err := d.db.QueryRowContext(ctx, query, args...).Scan(&amount); err != nil {
+		return 0, fmt.Errorf("counting jokes error: %w", err)
+	}
+
+	return amount, nil
+}
+
+func (d *Database) query(ctx context.Context, query string, args ...interface{}) ([]models.Joke, error) {
+	result := []models.Joke{}
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result, fmt.Errorf("querying jokes error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var joke models.Joke
+
+		if err := rows.Scan(&joke.ID, &joke.Title, &joke.Body, &joke.Score, &joke.Position); err != nil {
+			return result, fmt.Errorf("scanning joke error: %w", err)
+		}
+
+		result = append(result, joke)
+	}
+
+	return result, rows.Err()
+}
+
+func (d *Database) queryOne(ctx context.Context, query string, args ...interface{}) (models.Joke, error) {
+	var joke models.Joke
+
+	err := d.db.QueryRowContext(ctx, query, args...).Scan(&joke.ID, &joke.Title, &joke.Body, &joke.Score, &joke.Position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Joke{}, storage.ErrJokeNotFound
+	}
+
+	if err != nil {
+		return models.Joke{}, fmt.Errorf("querying joke error: %w", err)
+	}
+
+	return joke, nil
+}
+
+// filterConditions returns the conditions of the filter, the parameters are numbered from n on.
+func filterConditions(n int, filter storage.Filter) ([]string, []interface{}) {
+	var conditions []string
+
+	var args []interface{}
+
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, fmt.Sprintf(condition, n+len(args)))
+		args = append(args, arg)
+	}
+
+	if filter.MinScore != nil {
+		add("score >= $%d", *filter.MinScore)
+	}
+	if filter.MaxScore != nil {
+		add("score <= $%d", *filter.MaxScore)
+	}
+	if filter.MinBodyLength != nil {
+		add("char_length(body) >= $%d", *filter.MinBodyLength)
+	}
+	if filter.MaxBodyLength != nil {
+		add("char_length(body) <= $%d", *filter.MaxBodyLength)
+	}
+
+	if filter.HasBody != nil {
+		if *filter.HasBody {
+			conditions = append(conditions, "body <> ''")
+		} else {
+			conditions = append(conditions, "body = ''")
+		}
+	}
+
+	return conditions, args
+}
+
+// orderBy returns the ORDER BY expressions of the order, ties are broken by seq, which keeps the insertion order.
+// The random order sorts the random numbers stored with the jokes in the permutation of the seed, see storage.ShuffleFactors.
+func orderBy(order storage.Sort, seed int64) string {
+	switch order {
+	case storage.SortScore:
+		return "score, seq"
+	case storage.SortScoreDesc:
+		return "score DESC, seq"
+	case storage.SortTitle:
+		return "lower(title), seq"
+	case storage.SortRandom:
+		a, b := storage.ShuffleFactors(seed)
+
+		return fmt.Sprintf("(rand * %d + %d) %% %d, seq", a, b, storage.RandModulus)
+	default:
+		return "seq"
+	}
+}
+
+// cursorCondition returns the condition on the jokes following the cursor in the order and its arguments,
+// the parameters are numbered from n on.
+func cursorCondition(order storage.Sort, n int, after storage.Cursor) (string, []interface{}) {
+	switch order {
+	case storage.SortScore:
+		return fmt.Sprintf("(score > $%d OR (score = $%d AND seq > $%d))", n, n, n+1), []interface{}{after.Score, after.Position}
+	case storage.SortScoreDesc:
+		return fmt.Sprintf("(score < $%d OR (score = $%d AND seq > $%d))", n, n, n+1), []interface{}{after.Score, after.Position}
+	default:
+		return fmt.Sprintf("seq > $%d", n), []interface{}{after.Position}
+	}
+}
+
+// where joins the conditions into a WHERE clause, no conditions give an empty clause.
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// tsQuery turns user input into a tsquery, in which every word is a prefix term,
+// so the input can never be interpreted as tsquery syntax.
+func tsQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, word+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// Truncate removes all jokes, it is meant for tests and data imports.
+func (d *Database) Truncate(ctx context.Context) error {
+	defer d.vocabulary.Invalidate()
+
+	if _, err := d.db.ExecContext(ctx, "TRUNCATE jokes RESTART IDENTITY"); err != nil {
+		return fmt.Errorf("truncating jokes error: %w", err)
+	}
+
+	return nil
+}
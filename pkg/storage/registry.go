@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+)
+
+// ErrUnknownDriver describes the error when no storage driver is registered by the requested name.
+var ErrUnknownDriver = errors.New("unknown storage driver")
+
+// Factory creates a Storage from the configuration.
+type Factory func(cfg config.Config) (Storage, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a storage driver available by the provided name.
+// It panics if Register is called twice with the same name or if factory is nil.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+
+	if _, dup := factories[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+
+	factories[name] = factory
+}
+
+// Drivers returns a sorted list of the names of the registered drivers.
+func Drivers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Open creates the storage of the driver registered by the name.
+func Open(name string, cfg config.Config) (Storage, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w %q, available drivers: %s", ErrUnknownDriver, name, strings.Join(Drivers(), ", "))
+	}
+
+	s, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("storage driver %q: %w", name, err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,49 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStorage struct {
+	storage.Storage
+	path string
+}
+
+func TestRegistry(t *testing.T) {
+	storage.Register("fake", func(cfg config.Config) (storage.Storage, error) {
+		if err := cfg.Require("FILE_PATH"); err != nil {
+			return nil, err
+		}
+
+		return fakeStorage{path: cfg.FilePath}, nil
+	})
+	storage.Register("broken", func(cfg config.Config) (storage.Storage, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	assert.Subset(t, storage.Drivers(), []string{"broken", "fake"})
+
+	s, err := storage.Open("fake", config.Config{FilePath: "jokes.json"})
+	require.NoError(t, err)
+	assert.EqualValues(t, fakeStorage{path: "jokes.json"}, s)
+
+	_, err = storage.Open("fake", config.Config{})
+	require.ErrorIs(t, err, config.ErrMissingSetting)
+	assert.EqualError(t, err, `storage driver "fake": missing required settings: FILE_PATH`)
+
+	_, err = storage.Open("broken", config.Config{})
+	assert.EqualError(t, err, `storage driver "broken": connection refused`)
+
+	_, err = storage.Open("unknown", config.Config{})
+	assert.ErrorIs(t, err, storage.ErrUnknownDriver)
+
+	assert.Panics(t, func() {
+		storage.Register("fake", func(cfg config.Config) (storage.Storage, error) { return nil, nil })
+	})
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+)
+
+// SearchMode selects how GetJokesByText interprets the search text.
+type SearchMode string
+
+const (
+	// SearchPlain matches every word of the text as a case-insensitive prefix of a word in the title or the body,
+	// all of the words have to match. Everything but letters and digits separates words, the text is never
+	// interpreted as a pattern or query syntax. It is the default.
+	SearchPlain SearchMode = "plain"
+	// SearchRegex matches the text as a case-insensitive regular expression, see CompilePattern.
+	SearchRegex SearchMode = "regex"
+	// SearchText matches whole words with the full-text index of the storage.
+	SearchText SearchMode = "text"
+	// SearchFuzzy matches the words of the text with typos, see MaxEdits. Every word has to match a whole word.
+	SearchFuzzy SearchMode = "fuzzy"
+)
+
+// ErrUnknownSearchMode describes the error when the search mode is not supported.
+var ErrUnknownSearchMode = errors.New("unknown search mode")
+
+// ErrInvalidPattern describes the error when a regular expression is rejected by CompilePattern.
+var ErrInvalidPattern = errors.New("invalid search pattern")
+
+// RegexTimeout bounds the time a storage spends on a regular expression search.
+const RegexTimeout = 500 * time.Millisecond
+
+// maxPatternLength limits the length of regular expressions in bytes.
+const maxPatternLength = 256
+
+// ParseSearchMode returns the search mode named s, the empty string selects SearchPlain.
+func ParseSearchMode(s string) (SearchMode, error) {
+	switch mode := SearchMode(s); mode {
+	case "":
+		return SearchPlain, nil
+	case SearchPlain, SearchRegex, SearchText, SearchFuzzy:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownSearchMode, s)
+	}
+}
+
+// CompilePattern validates a user supplied regular expression and compiles it case-insensitively.
+// Only the RE2 syntax is accepted, so the pattern means the same in every storage. Repeated parts of
+// the pattern must match in a single way: nested repetitions like (a+)+, optional parts like (aa?)+
+// and alternations like (a|aa)+ are rejected, as they make backtracking engines run in exponential time.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > maxPatternLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidPattern, maxPatternLength)
+	}
+
+	tree, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPattern, err)
+	}
+
+	if nestedRepeat(tree, false) {
+		return nil, fmt.Errorf("%w: nested repetitions are not allowed", ErrInvalidPattern)
+	}
+
+	// the parser merges alternations like \w|\d into a single class, so they are looked for in the source.
+	if optionalRepeat(tree, false) || repeatedAlternation(pattern) {
+		return nil, fmt.Errorf("%w: optional parts and alternations are not allowed in repetitions", ErrInvalidPattern)
+	}
+
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPattern, err)
+	}
+	return re, nil
+}
+
+func nestedRepeat(re *syntax.Regexp, inRepeat bool) bool {
+	repeat := isRepeat(re)
+	if repeat && inRepeat {
+		return true
+	}
+
+	for _, sub := range re.Sub {
+		if nestedRepeat(sub, inRepeat || repeat) {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalRepeat reports a repetition of an optional part or an alternation, which match the same text in more than one way.
+func optionalRepeat(re *syntax.Regexp, inRepeat bool) bool {
+	optional := re.Op == syntax.OpQuest || re.Op == syntax.OpAlternate || (re.Op == syntax.OpRepeat && re.Min != re.Max)
+	if optional && inRepeat {
+		return true
+	}
+
+	for _, sub := range re.Sub {
+		if optionalRepeat(sub, inRepeat || isRepeat(re)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRepeat(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpStar || re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Max != 1)
+}
+
+// repeatedAlternation reports a group containing an alternation, which is followed by a repetition.
+func repeatedAlternation(pattern string) bool {
+	// groups holds whether the open groups contain an alternation.
+	groups := []bool{false}
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if strings.HasPrefix(pattern[i:], `\Q`) {
+				end := strings.Index(pattern[i:], `\E`)
+				if end < 0 {
+					return false
+				}
+				i += end + 1
+			} else {
+				i++
+			}
+		case '[':
+			i = classEnd(pattern, i)
+		case '(':
+			groups = append(groups, false)
+		case ')':
+			if len(groups) == 1 {
+				return false
+			}
+
+			alternation := groups[len(groups)-1]
+			groups = groups[:len(groups)-1]
+			if alternation && repeatFollows(pattern[i+1:]) {
+				return true
+			}
+			groups[len(groups)-1] = groups[len(groups)-1] || alternation
+		case '|':
+			groups[len(groups)-1] = true
+		}
+	}
+	return false
+}
+
+// classEnd returns the index of the bracket closing the character class opened at start.
+func classEnd(pattern string, start int) int {
+	i := start + 1
+	if i < len(pattern) && pattern[i] == '^' {
+		i++
+	}
+	// a bracket right after the opening one belongs to the class.
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+
+	for ; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\':
+			i++
+		case strings.HasPrefix(pattern[i:], "[:"):
+			if end := strings.Index(pattern[i+2:], ":]"); end >= 0 {
+				i += end + 3
+			}
+		case pattern[i] == ']':
+			return i
+		}
+	}
+	return len(pattern)
+}
+
+// repeatFollows reports whether s starts with a quantifier matching more than once.
+func repeatFollows(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	switch s[0] {
+	case '*', '+':
+		return true
+	case '{':
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return false
+		}
+
+		bounds := strings.SplitN(s[1:end], ",", 2)
+		max := bounds[len(bounds)-1]
+		return max == "" || max != "1" && max != "0"
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package storage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSearchMode(t *testing.T) {
+	for s, want := range map[string]storage.SearchMode{
+		"":      storage.SearchPlain,
+		"plain": storage.SearchPlain,
+		"regex": storage.SearchRegex,
+		"text":  storage.SearchText,
+		"fuzzy": storage.SearchFuzzy,
+	} {
+		mode, err := storage.ParseSearchMode(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, mode, s)
+	}
+
+	_, err := storage.ParseSearchMode("REGEX")
+	assert.ErrorIs(t, err, storage.ErrUnknownSearchMode)
+}
+
+func TestCompilePattern(t *testing.T) {
+	re, err := storage.CompilePattern("h.rse")
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("HORSE"), "patterns are case-insensitive")
+
+	for _, pattern := range []string{"[ab]*c", "(a|b)c*", `\bbar(tender)?`, "x{2,3}", `(\w\d)+$`, `([|(]x)+`, `(a|b){1}`, `(\Q|\E)+`} {
+		_, err := storage.CompilePattern(pattern)
+		assert.NoError(t, err, pattern)
+	}
+
+	for _, pattern := range []string{"(", "(a+)+$", "(a*)*", "(?:a{2,}){3}", `(\w+\s?)*$`,
+		"(a|aa)+$", "(aa?)+$", `(\w|\d)+$`, "((a|b)c)*", "(a|b){2,}", `(x(?:ab|cd))+`, strings.Repeat("a", 257)} {
+		_, err := storage.CompilePattern(pattern)
+		assert.ErrorIs(t, err, storage.ErrInvalidPattern, pattern)
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+)
+
+// Sort orders the jokes of a listing. Jokes, which are equal in the order, keep the insertion order.
+type Sort string
+
+const (
+	// SortDefault keeps the order of the listing: GetJokes sorts by SortCreated, GetFunniestJokes by
+	// SortScoreDesc and GetJokesByText by SortRelevance.
+	SortDefault Sort = ""
+	// SortCreated orders the jokes by insertion.
+	SortCreated Sort = "created"
+	// SortScore orders the jokes from the lowest score.
+	SortScore Sort = "score"
+	// SortScoreDesc orders the jokes from the highest score.
+	SortScoreDesc Sort = "-score"
+	// SortTitle orders the jokes by their titles case-insensitively.
+	SortTitle Sort = "title"
+	// SortRandom shuffles the jokes in the order given by Filter.RandSeed, the same seed gives the same order.
+	SortRandom Sort = "random"
+	// SortRelevance orders found jokes from the best match, only GetJokesByText supports it.
+	SortRelevance Sort = "relevance"
+)
+
+// ErrInvalidSort describes the error when the sort is unknown or not supported by the listing.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// ParseSort returns the sort named s, the empty string selects SortDefault.
+func ParseSort(s string) (Sort, error) {
+	switch sort := Sort(s); sort {
+	case SortDefault, SortCreated, SortScore, SortScoreDesc, SortTitle, SortRandom, SortRelevance:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidSort, s)
+	}
+}
+
+// SupportsCursor reports whether Filter.After can continue a listing in the order.
+func (s Sort) SupportsCursor() bool {
+	return s == SortCreated || s == SortScore || s == SortScoreDesc
+}
+
+// ListingSort returns the order of GetJokes or GetFunniestJokes, def replaces SortDefault.
+// It rejects relevance, which only orders searches, and cursors the order does not support.
+func ListingSort(filter Filter, def Sort) (Sort, error) {
+	sort := filter.Sort
+	if sort == SortDefault {
+		sort = def
+	}
+
+	if sort == SortRelevance {
+		return "", fmt.Errorf("%w: only searches are ordered by relevance", ErrInvalidSort)
+	}
+
+	if filter.After != nil && !sort.SupportsCursor() {
+		return "", fmt.Errorf("%w: the %s order does not support cursors", ErrInvalidCursor, sort)
+	}
+
+	return sort, nil
+}
+
+// ShuffleKey returns the key of the joke with the id in the random order given by seed.
+// Storages without a shuffle of their own sort by it, so the order only depends on the seed.
+func ShuffleKey(seed int64, id string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatInt(seed, 10) + ":" + id))
+
+	return int64(h.Sum64() >> 1)
+}
+
+// RandModulus is a prime, the databases keep a random number below it with every joke and the seeds of
+// the random orders map these numbers onto a permutation of their values, see ShuffleFactors.
+// Products of two numbers below it fit into an int64.
+const RandModulus = 1<<31 - 1
+
+// NewRand returns the random number of a new joke.
+func NewRand() int64 {
+	return rand.Int63n(RandModulus)
+}
+
+// ShuffleFactors returns the factors of the permutation (rand * a + b) mod RandModulus of the random numbers
+// made by the seed, a is never zero, so no two random numbers are mapped onto the same value.
+func ShuffleFactors(seed int64) (int64, int64) {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatInt(seed, 10)))
+	sum := h.Sum64()
+
+	return 1 + int64(sum%(RandModulus-1)), int64((sum >> 32) % RandModulus)
+}
@@ -0,0 +1,71 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSort(t *testing.T) {
+	for _, s := range []string{"", "created", "score", "-score", "title", "random", "relevance"} {
+		sort, err := storage.ParseSort(s)
+		require.NoError(t, err, s)
+		assert.EqualValues(t, s, sort)
+	}
+
+	for _, s := range []string{"Score", "+score", "-title", "id"} {
+		_, err := storage.ParseSort(s)
+		assert.ErrorIs(t, err, storage.ErrInvalidSort, s)
+	}
+}
+
+func TestListingSort(t *testing.T) {
+	sort, err := storage.ListingSort(storage.Filter{}, storage.SortScoreDesc)
+	require.NoError(t, err)
+	assert.Equal(t, storage.SortScoreDesc, sort)
+
+	sort, err = storage.ListingSort(storage.Filter{Sort: storage.SortTitle}, storage.SortCreated)
+	require.NoError(t, err)
+	assert.Equal(t, storage.SortTitle, sort)
+
+	_, err = storage.ListingSort(storage.Filter{Sort: storage.SortRelevance}, storage.SortCreated)
+	assert.ErrorIs(t, err, storage.ErrInvalidSort)
+
+	cursor := &storage.Cursor{Position: 1}
+	for _, s := range []storage.Sort{storage.SortDefault, storage.SortCreated, storage.SortScore, storage.SortScoreDesc} {
+		_, err := storage.ListingSort(storage.Filter{Sort: s, After: cursor}, storage.SortCreated)
+		assert.NoError(t, err, s)
+	}
+
+	for _, s := range []storage.Sort{storage.SortTitle, storage.SortRandom} {
+		_, err := storage.ListingSort(storage.Filter{Sort: s, After: cursor}, storage.SortCreated)
+		assert.ErrorIs(t, err, storage.ErrInvalidCursor, s)
+	}
+}
+
+func TestShuffleKey(t *testing.T) {
+	assert.Equal(t, storage.ShuffleKey(7, "5tz52q"), storage.ShuffleKey(7, "5tz52q"))
+	assert.NotEqual(t, storage.ShuffleKey(7, "5tz52q"), storage.ShuffleKey(8, "5tz52q"))
+	assert.NotEqual(t, storage.ShuffleKey(7, "5tz52q"), storage.ShuffleKey(7, "1a7xnd"))
+	assert.GreaterOrEqual(t, storage.ShuffleKey(-1, ""), int64(0), "keys are never negative")
+}
+
+func TestShuffleFactors(t *testing.T) {
+	for _, seed := range []int64{-1, 0, 1, 42, 1 << 62} {
+		a, b := storage.ShuffleFactors(seed)
+		assert.True(t, a > 0 && a < storage.RandModulus, "a of seed %d is a nonzero number below the modulus", seed)
+		assert.True(t, b >= 0 && b < storage.RandModulus, "b of seed %d is below the modulus", seed)
+
+		again, _ := storage.ShuffleFactors(seed)
+		assert.Equal(t, a, again)
+	}
+
+	a, b := storage.ShuffleFactors(1)
+	c, d := storage.ShuffleFactors(2)
+	assert.NotEqual(t, [2]int64{a, b}, [2]int64{c, d}, "seeds make their own permutations")
+
+	n := storage.NewRand()
+	assert.True(t, n >= 0 && n < storage.RandModulus)
+}
@@ -0,0 +1,16 @@
+package sqlite
+
+import (
+	"github.com/DanilLagunov/jokes-api/pkg/config"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+func init() {
+	storage.Register("sqlite", func(cfg config.Config) (storage.Storage, error) {
+		if err := cfg.Require("SQLITE_PATH"); err != nil {
+			return nil, err
+		}
+
+		return NewDatabase(cfg.SQLitePath)
+	})
+}
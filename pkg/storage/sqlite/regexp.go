@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"modernc.org/sqlite"
+)
+
+// maxCachedPatterns bounds the number of compiled patterns kept between calls.
+const maxCachedPatterns = 64
+
+// SQLite has no regular expression implementation of its own, "X REGEXP Y" calls the user function regexp(Y, X).
+// regexp_count(Y, X) returns the number of matches of Y in X and is used to rank the results.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		re, text, err := patternArgs(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return re.MatchString(text), nil
+	})
+
+	sqlite.MustRegisterDeterministicScalarFunction("regexp_count", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		re, text, err := patternArgs(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return int64(len(re.FindAllStringIndex(text, -1))), nil
+	})
+}
+
+var patterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: map[string]*regexp.Regexp{}}
+
+func patternArgs(args []driver.Value) (*regexp.Regexp, string, error) {
+	pattern, ok := args[0].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("regexp: pattern has to be a string, got %T", args[0])
+	}
+
+	text, _ := args[1].(string)
+
+	patterns.Lock()
+	defer patterns.Unlock()
+
+	if re, ok := patterns.compiled[pattern]; ok {
+		return re, text, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(patterns.compiled) >= maxCachedPatterns {
+		patterns.compiled = map[string]*regexp.Regexp{}
+	}
+	patterns.compiled[pattern] = re
+
+	return re, text, nil
+}
@@ -0,0 +1,567 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+
+	// registers the pure-Go "sqlite" driver.
+	_ "modernc.org/sqlite"
+)
+
+// schema is applied on every start, so all statements have to be idempotent.
+// The seq column keeps the insertion order, unlike an implicit rowid it is neither renumbered by VACUUM
+// nor reused after the newest joke is deleted. The rand column is the base of the random orders, see orderBy.
+// The jokes_fts table is an external content FTS5 index kept in sync by triggers, jokes_vocab lists its terms
+// for the fuzzy search.
+const schema = `
+CREATE TABLE IF NOT EXISTS jokes (
+	seq   INTEGER PRIMARY KEY AUTOINCREMENT,
+	id    TEXT UNIQUE NOT NULL,
+	title TEXT NOT NULL,
+	body  TEXT NOT NULL,
+	score INTEGER NOT NULL DEFAULT 0,
+	rand  INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS jokes_score_idx ON jokes (score DESC);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS jokes_fts USING fts5 (
+	title, body, content = 'jokes', content_rowid = 'seq'
+);
+
+CREATE TRIGGER IF NOT EXISTS jokes_ai AFTER INSERT ON jokes BEGIN
+	INSERT INTO jokes_fts (rowid, title, body) VALUES (new.seq, new.title, new.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS jokes_ad AFTER DELETE ON jokes BEGIN
+	INSERT INTO jokes_fts (jokes_fts, rowid, title, body) VALUES ('delete', old.seq, old.title, old.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS jokes_au AFTER UPDATE OF title, body ON jokes BEGIN
+	INSERT INTO jokes_fts (jokes_fts, rowid, title, body) VALUES ('delete', old.seq, old.title, old.body);
+	INSERT INTO jokes_fts (rowid, title, body) VALUES (new.seq, new.title, new.body);
+END;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS jokes_vocab USING fts5vocab (jokes_fts, 'row');
+`
+
+// randomNumber is a random number below storage.RandModulus for the jokes stored before the rand column.
+const randomNumber = "(random() & 9223372036854775807) % 2147483647"
+
+// upgradeSeq moves the jokes of a database created before the seq column into the current schema.
+// The jokes keep their rowids as seq, so the cursors handed out before stay valid.
+const upgradeSeq = `
+DROP TRIGGER IF EXISTS jokes_ai;
+DROP TRIGGER IF EXISTS jokes_ad;
+DROP TRIGGER IF EXISTS jokes_au;
+DROP TABLE IF EXISTS jokes_vocab;
+DROP TABLE IF EXISTS jokes_fts;
+DROP INDEX IF EXISTS jokes_score_idx;
+ALTER TABLE jokes RENAME TO jokes_old;
+` + schema + `
+INSERT INTO jokes (seq, id, title, body, score, rand)
+	SELECT rowid, id, title, body, score, ` + randomNumber + ` FROM jokes_old;
+DROP TABLE jokes_old;
+`
+
+// upgradeRand gives the jokes of a database created before the rand column their random numbers.
+const upgradeRand = `
+ALTER TABLE jokes ADD COLUMN rand INTEGER NOT NULL DEFAULT 0;
+UPDATE jokes SET rand = ` + randomNumber + `;
+` + schema
+
+const jokeColumns = "id, title, body, score, seq"
+
+// maxIDAttempts limits the number of attempts to generate a unique id for a new joke.
+const maxIDAttempts = 10
+
+// Database struct.
+type Database struct {
+	db         *sql.DB
+	vocabulary storage.Vocabulary
+}
+
+// NewDatabase opens the SQLite database stored in the file at path and creates the schema if needed.
+func NewDatabase(path string) (*Database, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("opening database error: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return &Database{db: db}, nil
+}
+
+// migrate creates the schema or upgrades the schema of an older database.
+func migrate(db *sql.DB) error {
+	columns, err := tableColumns(db, "jokes")
+	if err != nil {
+		return err
+	}
+
+	script := schema
+
+	switch {
+	case len(columns) == 0:
+	case !columns["seq"]:
+		script = upgradeSeq
+	case !columns["rand"]:
+		script = upgradeRand
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("creating schema error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("creating schema error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("creating schema error: %w", err)
+	}
+
+	return nil
+}
+
+// tableColumns returns the names of the columns of the table, a missing table has none.
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema error: %w", err)
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("reading schema error: %w", err)
+		}
+		columns[name] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading schema error: %w", err)
+	}
+
+	return columns, nil
+}
+
+// Close closes the database.
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// GetJokes method returns a number of jokes given by skip and limit parameters and total amount of jokes.
+func (d *Database) GetJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, filter, storage.SortCreated)
+}
+
+// AddJoke method creating new joke.
+func (d *Database) AddJoke(ctx context.Context, title, body string, score int) (models.Joke, error) {
+	defer d.vocabulary.Invalidate()
+
+	for i := 0; i < maxIDAttempts; i++ {
+		id, err := models.GenerateID()
+		if err != nil {
+			return models.Joke{}, fmt.Errorf("ID generating error: %w", err)
+		}
+
+		res, err := d.db.ExecContext(ctx,
+			"INSERT INTO jokes (id, title, body, score, rand) VALUES (?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING",
+			id, title, body, score, storage.NewRand())
+		if err != nil {
+			return models.Joke{}, fmt.Errorf("inserting joke error: %w", err)
+		}
+
+		if inserted, err := res.RowsAffected(); err == nil && inserted > 0 {
+			joke := models.NewJoke(id, title, body, score)
+			if joke.Position, err = res.LastInsertId(); err != nil {
+				return models.Joke{}, fmt.Errorf("inserting joke error: %w", err)
+			}
+
+			return joke, nil
+		}
+	}
+
+	return models.Joke{}, errors.New("ID generating error: no unique id found")
+}
+
+// GetJokesByText returns a number of jokes, which contain the desired words, given by skip and limit parameters
+// and total amount of found jokes. In the plain and text modes every word of the text is matched as a case-insensitive
+// prefix and jokes are ranked by bm25, matches in the title weigh twice as much as matches in the body.
+// In the regex mode the text is a pattern checked with storage.CompilePattern and jokes are ranked by the number of matches.
+// In the fuzzy mode every word matches the indexed terms with a few typos, see storage.MaxEdits, and jokes are ranked by bm25.
+func (d *Database) GetJokesByText(ctx context.Context, skip, limit int, text string, mode storage.SearchMode, filter storage.Filter) ([]models.Joke, int, error) {
+	var match string
+
+	switch mode {
+	case storage.SearchPlain, storage.SearchText:
+		match = matchExpression(text)
+	case storage.SearchRegex:
+		return d.getJokesByPattern(ctx, skip, limit, text, filter)
+	case storage.SearchFuzzy:
+		var err error
+		if match, err = d.fuzzyExpression(ctx, text); err != nil {
+			return []models.Joke{}, 0, err
+		}
+	default:
+		return []models.Joke{}, 0, fmt.Errorf("%w: %q", storage.ErrUnknownSearchMode, mode)
+	}
+
+	if match == "" {
+		return []models.Joke{}, 0, nil
+	}
+
+	// a column filter restricts the whole expression to the column.
+	if filter.Field == storage.FieldTitle || filter.Field == storage.FieldBody {
+		match = string(filter.Field) + " : (" + match + ")"
+	}
+
+	conditions, args := filterConditions("j.", 2, filter)
+	conditions = append([]string{"jokes_fts MATCH ?1"}, conditions...)
+	args = append([]interface{}{match}, args...)
+
+	from := " FROM jokes_fts JOIN jokes j ON j.seq = jokes_fts.rowid" + where(conditions)
+
+	amount, err := d.count(ctx, "SELECT COUNT(*)"+from, args...)
+	if err != nil {
+		return []models.Joke{}, amount, err
+	}
+
+	order := "bm25(jokes_fts, 2.0, 1.0), j.seq"
+	if filter.Sort != storage.SortDefault && filter.Sort != storage.SortRelevance {
+		order = orderBy("j.", filter.Sort, filter.RandSeed)
+	}
+
+	n := len(args) + 1
+	result, err := d.query(ctx, "SELECT j.id, j.title, j.body, j.score, j.seq"+from+
+		" ORDER BY "+order+fmt.Sprintf(" LIMIT ?%d OFFSET ?%d", n, n+1), append(args, limit, skip)...)
+
+	return result, amount, err
+}
+
+// fuzzyExpression returns the FTS5 query matching the terms close to every word of the text or
+// an empty string, when some word has no close term.
+func (d *Database) fuzzyExpression(ctx context.Context, text string) (string, error) {
+	vocabulary, err := d.vocabulary.Terms(ctx, d.terms)
+	if err != nil {
+		return "", err
+	}
+
+	groups := storage.FuzzyTerms(text, vocabulary)
+
+	expressions := make([]string, 0, len(groups))
+	for _, terms := range groups {
+		for i, term := range terms {
+			terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		}
+		expressions = append(expressions, "("+strings.Join(terms, " OR ")+")")
+	}
+
+	return strings.Join(expressions, " AND "), nil
+}
+
+// terms returns the indexed terms of all jokes.
+func (d *Database) terms(ctx context.Context) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT term FROM jokes_vocab")
+	if err != nil {
+		return nil, fmt.Errorf("querying terms error: %w", err)
+	}
+	defer rows.Close()
+
+	var vocabulary []string
+
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, fmt.Errorf("scanning term error: %w", err)
+		}
+		vocabulary = append(vocabulary, term)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying terms error: %w", err)
+	}
+
+	return vocabulary, nil
+}
+
+func (d *Database) getJokesByPattern(ctx context.Context, skip, limit int, pattern string, filter storage.Filter) ([]models.Joke, int, error) {
+	re, err := storage.CompilePattern(pattern)
+	if err != nil {
+		return []models.Joke{}, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storage.RegexTimeout)
+	defer cancel()
+
+	var matches, counts []string
+	for _, column := range []string{"title", "body"} {
+		if filter.Field == storage.FieldAny || string(filter.Field) == column {
+			matches = append(matches, column+" REGEXP ?1")
+			counts = append(counts, "regexp_count(?1, "+column+")")
+		}
+	}
+
+	conditions, args := filterConditions("", 2, filter)
+	conditions = append([]string{"(" + strings.Join(matches, " OR ") + ")"}, conditions...)
+	args = append([]interface{}{re.String()}, args...)
+
+	amount, err := d.count(ctx, "SELECT COUNT(*) FROM jokes"+where(conditions), args...)
+	if err != nil {
+		return []models.Joke{}, amount, err
+	}
+
+	order := strings.Join(counts, " + ") + " DESC, seq"
+	if filter.Sort != storage.SortDefault && filter.Sort != storage.SortRelevance {
+		order = orderBy("", filter.Sort, filter.RandSeed)
+	}
+
+	n := len(args) + 1
+	result, err := d.query(ctx, "SELECT "+jokeColumns+" FROM jokes"+where(conditions)+
+		" ORDER BY "+order+fmt.Sprintf(" LIMIT ?%d OFFSET ?%d", n, n+1), append(args, limit, skip)...)
+
+	return result, amount, err
+}
+
+// GetJokeByID returns joke that has the same id.
+func (d *Database) GetJokeByID(ctx context.Context, id string) (models.Joke, error) {
+	return d.queryOne(ctx, "SELECT "+jokeColumns+" FROM jokes WHERE id = ?", id)
+}
+
+// GetRandomJokes returns number of random jokes given by skip and limit parameters and total amount of jokes.
+// The jokes are shuffled in the SortRandom order of randSeed, so the same seed pages through the same permutation.
+func (d *Database) GetRandomJokes(ctx context.Context, skip, limit int, randSeed int64) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, storage.Filter{Sort: storage.SortRandom, RandSeed: randSeed}, storage.SortRandom)
+}
+
+// GetFunniestJokes returns number of sorted jokes given by skip and limit parameters and total amount of jokes.
+func (d *Database) GetFunniestJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	return d.list(ctx, skip, limit, filter, storage.SortScoreDesc)
+}
+
+// list returns the page of the jokes passing the filter in its order, def is the order of the listing.
+func (d *Database) list(ctx context.Context, skip, limit int, filter storage.Filter, def storage.Sort) ([]models.Joke, int, error) {
+	order, err := storage.ListingSort(filter, def)
+	if err != nil {
+		return []models.Joke{}, 0, err
+	}
+
+	conditions, args := filterConditions("", 1, filter)
+
+	amount, err := d.count(ctx, "SELECT COUNT(*) FROM jokes"+where(conditions), args...)
+	if err != nil {
+		return []models.Joke{}, amount, err
+	}
+
+	if filter.After != nil {
+		condition, cursorArgs := cursorCondition(order, len(args)+1, *filter.After)
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	n := len(args) + 1
+	result, err := d.query(ctx, "SELECT "+jokeColumns+" FROM jokes"+where(conditions)+
+		" ORDER BY "+orderBy("", order, filter.RandSeed)+fmt.Sprintf(" LIMIT ?%d OFFSET ?%d", n, n+1),
+		append(args, limit, skip)...)
+
+	return result, amount, err
+}
+
+// UpdateJoke replaces title and body of the joke that has the same id and returns the updated joke.
+func (d *Database) UpdateJoke(ctx context.Context, id, title, body string) (models.Joke, error) {
+	defer d.vocabulary.Invalidate()
+
+	return d.queryOne(ctx,
+		"UPDATE jokes SET title = ?, body = ? WHERE id = ? RETURNING "+jokeColumns, title, body, id)
+}
+
+// DeleteJoke removes joke that has the same id.
+func (d *Database) DeleteJoke(ctx context.Context, id string) error {
+	defer d.vocabulary.Invalidate()
+
+	res, err := d.db.ExecContext(ctx, "DELETE FROM jokes WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("deleting joke error: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting joke error: %w", err)
+	}
+
+	if deleted == 0 {
+		return storage.ErrJokeNotFound
+	}
+
+	return nil
+}
+
+// IncrementScore atomically adds delta to the score of the joke that has the same id and returns the updated joke.
+func (d *Database) IncrementScore(ctx context.Context, id string, delta int) (models.Joke, error) {
+	return d.queryOne(ctx,
+		"UPDATE jokes SET score = score + ? WHERE id = ? RETURNING "+jokeColumns, delta, id)
+}
+
+func (d *Database) count(ctx context.Context, query string, args ...interface{}) (int, error) {
+	var amount int
+
+	if err := d.db.QueryRowContext(ctx, query, args...).Scan(&amount); err != nil {
+		return 0, fmt.Errorf("counting jokes error: %w", err)
+	}
+
+	return amount, nil
+}
+
+func (d *Database) query(ctx context.Context, query string, args ...interface{}) ([]models.Joke, error) {
+	result := []models.Joke{}
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result, fmt.Errorf("querying jokes error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var joke models.Joke
+
+		if err := rows.Scan(&joke.ID, &joke.Title, &joke.Body, &joke.Score, &joke.Position); err != nil {
+			return result, fmt.Errorf("scanning joke error: %w", err)
+		}
+
+		result = append(result, joke)
+	}
+
+	return result, rows.Err()
+}
+
+func (d *Database) queryOne(ctx context.Context, query string, args ...interface{}) (models.Joke, error) {
+	var joke models.Joke
+
+	err := d.db.QueryRowContext(ctx, query, args...).Scan(&joke.ID, &joke.Title, &joke.Body, &joke.Score, &joke.Position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Joke{}, storage.ErrJokeNotFound
+	}
+
+	if err != nil {
+		return models.Joke{}, fmt.Errorf("querying joke error: %w", err)
+	}
+
+	return joke, nil
+}
+
+// filterConditions returns the conditions of the filter on the columns of the jokes table, which are
+// prefixed with table. The parameters are numbered from n on.
+func filterConditions(table string, n int, filter storage.Filter) ([]string, []interface{}) {
+	var conditions []string
+
+	var args []interface{}
+
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, fmt.Sprintf(condition, table, n+len(args)))
+		args = append(args, arg)
+	}
+
+	if filter.MinScore != nil {
+		add("%sscore >= ?%d", *filter.MinScore)
+	}
+	if filter.MaxScore != nil {
+		add("%sscore <= ?%d", *filter.MaxScore)
+	}
+	if filter.MinBodyLength != nil {
+		add("length(%sbody) >= ?%d", *filter.MinBodyLength)
+	}
+	if filter.MaxBodyLength != nil {
+		add("length(%sbody) <= ?%d", *filter.MaxBodyLength)
+	}
+
+	if filter.HasBody != nil {
+		if *filter.HasBody {
+			conditions = append(conditions, table+"body != ''")
+		} else {
+			conditions = append(conditions, table+"body = ''")
+		}
+	}
+
+	return conditions, args
+}
+
+// orderBy returns the ORDER BY expressions of the order on the columns of the jokes table, which are prefixed
+// with table. Ties are broken by the seq, which keeps the insertion order. The random order sorts the random
+// numbers stored with the jokes in the permutation of the seed, see storage.ShuffleFactors.
+func orderBy(table string, order storage.Sort, seed int64) string {
+	switch order {
+	case storage.SortScore:
+		return table + "score, " + table + "seq"
+	case storage.SortScoreDesc:
+		return table + "score DESC, " + table + "seq"
+	case storage.SortTitle:
+		return "lower(" + table + "title), " + table + "seq"
+	case storage.SortRandom:
+		a, b := storage.ShuffleFactors(seed)
+
+		return fmt.Sprintf("(%srand * %d + %d) %% %d, %sseq", table, a, b, storage.RandModulus, table)
+	default:
+		return table + "seq"
+	}
+}
+
+// cursorCondition returns the condition on the jokes following the cursor in the order and its arguments,
+// the parameters are numbered from n on.
+func cursorCondition(order storage.Sort, n int, after storage.Cursor) (string, []interface{}) {
+	switch order {
+	case storage.SortScore:
+		return fmt.Sprintf("(score > ?%d OR (score = ?%d AND seq > ?%d))", n, n, n+1), []interface{}{after.Score, after.Position}
+	case storage.SortScoreDesc:
+		return fmt.Sprintf("(score < ?%d OR (score = ?%d AND seq > ?%d))", n, n, n+1), []interface{}{after.Score, after.Position}
+	default:
+		return fmt.Sprintf("seq > ?%d", n), []interface{}{after.Position}
+	}
+}
+
+// where joins the conditions into a WHERE clause, no conditions give an empty clause.
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// matchExpression turns user input into an FTS5 query, in which every word is a quoted prefix term,
+// so the input can never be interpreted as FTS5 query syntax.
+func matchExpression(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+
+	return strings.Join(terms, " ")
+}
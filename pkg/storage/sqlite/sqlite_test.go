@@ -0,0 +1,344 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/sqlite"
+	"github.com/DanilLagunov/jokes-api/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const requestTimeout time.Duration = time.Second * 2
+
+var (
+	dbPath       string
+	jokeID       string
+	jokePosition int64
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "jokes-api-sqlite")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbPath = filepath.Join(dir, "jokes.db")
+	prepareDBForTests()
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func prepareDBForTests() {
+	db, err := sqlite.NewDatabase(dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	db.AddJoke(ctx, "First joke", "Normal", 3)
+	db.AddJoke(ctx, "Second joke", "Incredible", 35)
+	joke, err := db.AddJoke(ctx, "Third joke", "Funny", 15)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jokeID = joke.ID
+	jokePosition = joke.Position
+}
+
+func openDB(t *testing.T) *sqlite.Database {
+	db, err := sqlite.NewDatabase(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestGetJokes(t *testing.T) {
+	db := openDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	result, amount, err := db.GetJokes(ctx, 0, 3, storage.Filter{})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, "First joke", result[0].Title)
+	assert.EqualValues(t, 3, amount)
+
+	result, amount, err = db.GetJokes(ctx, 1, 4, storage.Filter{})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, "Second joke", result[0].Title)
+	assert.EqualValues(t, 3, amount)
+
+	result, amount, err = db.GetJokes(ctx, 4, 4, storage.Filter{})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, []models.Joke{}, result)
+	assert.EqualValues(t, 3, amount)
+}
+
+func TestGetJokeByText(t *testing.T) {
+	db := openDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	tests := []struct {
+		Text     string
+		Expected []string
+	}{
+		{
+			Text:     "first",
+			Expected: []string{"First joke"},
+		},
+		{
+			Text:     "JOKE",
+			Expected: []string{"First joke", "Second joke", "Third joke"},
+		},
+		{
+			Text:     "incred",
+			Expected: []string{"Second joke"},
+		},
+		{
+			Text:     "funny third",
+			Expected: []string{"Third joke"},
+		},
+		{
+			Text:     "foo",
+			Expected: []string{},
+		},
+		{
+			Text:     `" OR *`,
+			Expected: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		result, amount, err := db.GetJokesByText(ctx, 0, 3, tc.Text, storage.SearchPlain, storage.Filter{})
+		require.NoError(t, err)
+		assert.EqualValues(t, len(tc.Expected), amount, tc.Text)
+		require.EqualValues(t, amount, len(result))
+		for i := 0; i < amount; i++ {
+			assert.EqualValues(t, tc.Expected[i], result[i].Title)
+		}
+	}
+
+	result, _, err := db.GetJokesByText(ctx, 4, 1, "first", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, []models.Joke{}, result)
+}
+
+func TestGetJokeByID(t *testing.T) {
+	db := openDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	result, err := db.GetJokeByID(ctx, jokeID)
+	require.NoError(t, err)
+	assert.EqualValues(t, models.Joke{ID: jokeID, Title: "Third joke", Body: "Funny", Score: 15, Position: jokePosition}, result)
+
+	_, err = db.GetJokeByID(ctx, "123456")
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+}
+
+func TestGetFunniestJokes(t *testing.T) {
+	db := openDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	expected := []int{35, 15, 3}
+
+	result, amount, err := db.GetFunniestJokes(ctx, 0, 3, storage.Filter{})
+	require.NoError(t, err)
+
+	for i := 0; i < amount; i++ {
+		assert.EqualValues(t, expected[i], result[i].Score)
+	}
+	assert.EqualValues(t, 3, amount)
+
+	result, _, err = db.GetFunniestJokes(ctx, 4, 4, storage.Filter{})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, []models.Joke{}, result)
+}
+
+func TestGetRandomJokes(t *testing.T) {
+	db := openDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	origin, _, err := db.GetJokes(ctx, 0, 3, storage.Filter{})
+	require.NoError(t, err)
+
+	shuffled := false
+	for i := 0; i < 100 && !shuffled; i++ {
+		random, size, err := db.GetRandomJokes(ctx, 0, 3, int64(i))
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, size)
+		require.Len(t, random, 3)
+		assert.ElementsMatch(t, origin, random)
+
+		shuffled = !assert.ObjectsAreEqual(origin, random)
+	}
+	if !shuffled {
+		t.Fatal("jokes not in random order")
+	}
+
+	random, _, err := db.GetRandomJokes(ctx, 0, 2, 42)
+	require.NoError(t, err)
+	assert.Len(t, random, 2)
+}
+
+func TestAddUpdateDeleteJoke(t *testing.T) {
+	db := openDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	joke, err := db.AddJoke(ctx, "Added joke", "New", 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, "Added joke", joke.Title)
+	assert.EqualValues(t, "New", joke.Body)
+
+	result, err := db.UpdateJoke(ctx, joke.ID, "Updated joke", "Edited")
+	require.NoError(t, err)
+	assert.EqualValues(t, models.Joke{ID: joke.ID, Title: "Updated joke", Body: "Edited", Position: joke.Position}, result)
+
+	found, amount, err := db.GetJokesByText(ctx, 0, 10, "edited", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, amount)
+	assert.EqualValues(t, []models.Joke{result}, found)
+
+	result, err = db.IncrementScore(ctx, joke.ID, -2)
+	require.NoError(t, err)
+	assert.EqualValues(t, -2, result.Score)
+
+	require.NoError(t, db.DeleteJoke(ctx, joke.ID))
+	assert.ErrorIs(t, db.DeleteJoke(ctx, joke.ID), storage.ErrJokeNotFound)
+
+	_, err = db.UpdateJoke(ctx, joke.ID, "Updated joke", "Edited")
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+
+	_, err = db.IncrementScore(ctx, joke.ID, 1)
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+
+	_, amount, err = db.GetJokesByText(ctx, 0, 10, "edited", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, amount)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		db, err := sqlite.NewDatabase(filepath.Join(t.TempDir(), "jokes.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return db
+	})
+}
+
+func TestUpgradeSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jokes.db")
+
+	legacy, err := sql.Open("sqlite", "file:"+path)
+	require.NoError(t, err)
+
+	_, err = legacy.Exec(`
+CREATE TABLE jokes (id TEXT PRIMARY KEY, title TEXT NOT NULL, body TEXT NOT NULL, score INTEGER NOT NULL DEFAULT 0);
+CREATE INDEX jokes_score_idx ON jokes (score DESC);
+CREATE VIRTUAL TABLE jokes_fts USING fts5 (title, body, content = 'jokes', content_rowid = 'rowid');
+INSERT INTO jokes (rowid, id, title, body, score) VALUES (3, 'a', 'Old joke', 'About a horse', 4), (7, 'b', 'Older joke', 'About a cat', 9);
+INSERT INTO jokes_fts (jokes_fts) VALUES ('rebuild');
+`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := sqlite.NewDatabase(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	result, _, err := db.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []models.Joke{
+		{ID: "a", Title: "Old joke", Body: "About a horse", Score: 4, Position: 3},
+		{ID: "b", Title: "Older joke", Body: "About a cat", Score: 9, Position: 7},
+	}, result, "the jokes keep their rowids as positions")
+
+	found, _, err := db.GetJokesByText(ctx, 0, 10, "horse", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, result[:1], found, "the search index is rebuilt")
+
+	added, err := db.AddJoke(ctx, "New joke", "Body", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), added.Position)
+
+	random, _, err := db.GetRandomJokes(ctx, 0, 10, 42)
+	require.NoError(t, err)
+	assert.Len(t, random, 3, "the upgraded jokes get random numbers")
+
+	reopened, err := sqlite.NewDatabase(path)
+	require.NoError(t, err, "the upgraded schema opens again")
+	reopened.Close()
+}
+
+func TestUpgradeSchemaRand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jokes.db")
+
+	legacy, err := sql.Open("sqlite", "file:"+path)
+	require.NoError(t, err)
+
+	_, err = legacy.Exec(`
+CREATE TABLE jokes (seq INTEGER PRIMARY KEY AUTOINCREMENT, id TEXT UNIQUE NOT NULL, title TEXT NOT NULL, body TEXT NOT NULL, score INTEGER NOT NULL DEFAULT 0);
+INSERT INTO jokes (seq, id, title, body, score) VALUES (3, 'a', 'Old joke', 'About a horse', 4), (7, 'b', 'Older joke', 'About a cat', 9);
+`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := sqlite.NewDatabase(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var orders [][]string
+	for seed := int64(0); seed < 20; seed++ {
+		random, amount, err := db.GetRandomJokes(ctx, 0, 10, seed)
+		require.NoError(t, err)
+		require.Equal(t, 2, amount)
+
+		ids := []string{random[0].ID, random[1].ID}
+		assert.ElementsMatch(t, []string{"a", "b"}, ids)
+		orders = append(orders, ids)
+	}
+
+	assert.Contains(t, orders, []string{"a", "b"}, "the upgraded jokes get their own random numbers")
+	assert.Contains(t, orders, []string{"b", "a"}, "the upgraded jokes get their own random numbers")
+}
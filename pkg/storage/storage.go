@@ -10,12 +10,15 @@ import (
 // ErrJokeNotFound describes the error when the joke is not found.
 var ErrJokeNotFound = errors.New("joke not found")
 
-// Storage interface.
+// Storage interface. The listings return the jokes passing the filter and their total amount.
 type Storage interface {
-	GetJokes(ctx context.Context, skip, seed int) ([]models.Joke, int, error)
+	GetJokes(ctx context.Context, skip, seed int, filter Filter) ([]models.Joke, int, error)
 	AddJoke(ctx context.Context, title, body string, score int) (models.Joke, error)
-	GetJokesByText(ctx context.Context, skip, seed int, text string) ([]models.Joke, int, error)
+	GetJokesByText(ctx context.Context, skip, seed int, text string, mode SearchMode, filter Filter) ([]models.Joke, int, error)
 	GetJokeByID(ctx context.Context, id string) (models.Joke, error)
-	GetRandomJokes(ctx context.Context, seed int) ([]models.Joke, int, error)
-	GetFunniestJokes(ctx context.Context, skip, seed int) ([]models.Joke, int, error)
+	GetRandomJokes(ctx context.Context, skip, seed int, randSeed int64) ([]models.Joke, int, error)
+	GetFunniestJokes(ctx context.Context, skip, seed int, filter Filter) ([]models.Joke, int, error)
+	UpdateJoke(ctx context.Context, id, title, body string) (models.Joke, error)
+	DeleteJoke(ctx context.Context, id string) error
+	IncrementScore(ctx context.Context, id string, delta int) (models.Joke, error)
 }
@@ -0,0 +1,826 @@
+// Package storagetest provides the behavioural contract every storage.Storage implementation has to satisfy.
+//
+// A backend runs the contract from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return newEmptyStorage(t)
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const requestTimeout time.Duration = time.Second * 5
+
+// Factory returns an empty storage for a single test, it is called once per subtest.
+// Resources of the storage should be released with t.Cleanup.
+type Factory func(t *testing.T) storage.Storage
+
+type fixture struct {
+	title string
+	body  string
+	score int
+}
+
+// fixtures are added in this order, their scores are distinct, so the funniest order is well defined.
+var fixtures = []fixture{
+	{"First joke", "A horse walks into a bar", 3},
+	{"Second joke", "The bartender asks why the long face", 35},
+	{"Third joke", "Nobody laughs at the HORSE", 15},
+	{"Fourth story", "It was not a joke at all", 7},
+}
+
+// Run runs the contract against the storages returned by newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{"EmptyStorage", testEmptyStorage},
+		{"GetJokes", testGetJokes},
+		{"GetJokesPagination", testGetJokesPagination},
+		{"AddJoke", testAddJoke},
+		{"GetJokeByID", testGetJokeByID},
+		{"GetJokesByText", testGetJokesByText},
+		{"GetJokesByTextPagination", testGetJokesByTextPagination},
+		{"GetJokesByTextRelevance", testGetJokesByTextRelevance},
+		{"GetJokesByTextPlainIsLiteral", testGetJokesByTextPlainIsLiteral},
+		{"GetJokesByTextPlainWords", testGetJokesByTextPlainWords},
+		{"GetJokesByTextRegex", testGetJokesByTextRegex},
+		{"GetJokesByTextInvalidPattern", testGetJokesByTextInvalidPattern},
+		{"GetJokesByTextIndex", testGetJokesByTextIndex},
+		{"GetJokesByTextFuzzy", testGetJokesByTextFuzzy},
+		{"GetJokesByTextUnknownMode", testGetJokesByTextUnknownMode},
+		{"GetRandomJokes", testGetRandomJokes},
+		{"GetFunniestJokes", testGetFunniestJokes},
+		{"Filter", testFilter},
+		{"FilterSearch", testFilterSearch},
+		{"Cursor", testCursor},
+		{"CursorFunniest", testCursorFunniest},
+		{"CursorDeletedJoke", testCursorDeletedJoke},
+		{"Sort", testSort},
+		{"SortRandom", testSortRandom},
+		{"SortSearch", testSortSearch},
+		{"UpdateJoke", testUpdateJoke},
+		{"DeleteJoke", testDeleteJoke},
+		{"IncrementScore", testIncrementScore},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+// seed adds the fixtures to s and returns the added jokes in insertion order.
+func seed(t *testing.T, s storage.Storage) []models.Joke {
+	ctx := testContext(t)
+
+	jokes := make([]models.Joke, 0, len(fixtures))
+	for _, f := range fixtures {
+		joke, err := s.AddJoke(ctx, f.title, f.body, f.score)
+		require.NoError(t, err)
+
+		jokes = append(jokes, joke)
+	}
+
+	return jokes
+}
+
+func titles(jokes []models.Joke) []string {
+	result := make([]string, 0, len(jokes))
+	for _, joke := range jokes {
+		result = append(result, joke.Title)
+	}
+
+	return result
+}
+
+func testEmptyStorage(t *testing.T, s storage.Storage) {
+	ctx := testContext(t)
+
+	result, amount, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+	assert.Equal(t, 0, amount)
+
+	result, amount, err = s.GetJokesByText(ctx, 0, 10, "joke", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+	assert.Equal(t, 0, amount)
+
+	result, amount, err = s.GetRandomJokes(ctx, 0, 10, 42)
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+	assert.Equal(t, 0, amount)
+
+	result, amount, err = s.GetFunniestJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+	assert.Equal(t, 0, amount)
+}
+
+func testGetJokes(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	result, amount, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, jokes, result, "jokes are returned in insertion order")
+	assert.Equal(t, len(jokes), amount)
+}
+
+func testGetJokesPagination(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	tests := []struct {
+		name        string
+		skip, limit int
+		want        []models.Joke
+	}{
+		{"first page", 0, 2, jokes[:2]},
+		{"middle page", 1, 2, jokes[1:3]},
+		{"last page is cut", 3, 2, jokes[3:]},
+		{"limit above amount", 0, 10, jokes},
+		{"skip equals amount", 4, 2, []models.Joke{}},
+		{"skip above amount", 10, 2, []models.Joke{}},
+		{"zero limit", 0, 0, []models.Joke{}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokes(ctx, tt.skip, tt.limit, storage.Filter{})
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, result, tt.name)
+		assert.Equal(t, len(jokes), amount, "%s: amount is the total, independent of the page", tt.name)
+	}
+}
+
+func testAddJoke(t *testing.T, s storage.Storage) {
+	ctx := testContext(t)
+
+	first, err := s.AddJoke(ctx, "Title", "Body", 5)
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, "Title", first.Title)
+	assert.Equal(t, "Body", first.Body)
+	assert.Equal(t, 5, first.Score)
+
+	second, err := s.AddJoke(ctx, "Title", "Body", 5)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID, "every joke gets its own id")
+
+	_, amount, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, amount)
+}
+
+func testGetJokeByID(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	for _, joke := range jokes {
+		result, err := s.GetJokeByID(ctx, joke.ID)
+		require.NoError(t, err)
+		assert.Equal(t, joke, result)
+	}
+
+	_, err := s.GetJokeByID(ctx, "does-not-exist")
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+}
+
+func testGetJokesByText(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"matches body", "horse", []string{"First joke", "Third joke"}},
+		{"case-insensitive", "HoRsE", []string{"First joke", "Third joke"}},
+		{"matches title", "second", []string{"Second joke"}},
+		{"matches title or body", "joke", []string{"First joke", "Second joke", "Third joke", "Fourth story"}},
+		{"matches word prefix", "bartend", []string{"Second joke"}},
+		{"no hits", "penguin", []string{}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokesByText(ctx, 0, 10, tt.text, storage.SearchPlain, storage.Filter{})
+		require.NoError(t, err, tt.name)
+		assert.NotNil(t, result, tt.name)
+		assert.ElementsMatch(t, tt.want, titles(result), tt.name)
+		assert.Equal(t, len(tt.want), amount, tt.name)
+	}
+}
+
+func testGetJokesByTextPagination(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	all, amount, err := s.GetJokesByText(ctx, 0, 10, "joke", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	assert.Equal(t, 4, amount)
+
+	result, amount, err := s.GetJokesByText(ctx, 1, 2, "joke", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, all[1:3], result)
+	assert.Equal(t, 4, amount)
+
+	result, amount, err = s.GetJokesByText(ctx, 3, 2, "joke", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, all[3:], result)
+	assert.Equal(t, 4, amount)
+
+	result, amount, err = s.GetJokesByText(ctx, 10, 2, "joke", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Equal(t, 4, amount)
+}
+
+func testGetJokesByTextRelevance(t *testing.T, s storage.Storage) {
+	ctx := testContext(t)
+
+	for _, f := range []fixture{
+		{"Plain", "Once there was a horse", 0},
+		{"Cow", "Moo", 0},
+		{"Horse", "Horse, horse and horse", 0},
+	} {
+		_, err := s.AddJoke(ctx, f.title, f.body, f.score)
+		require.NoError(t, err)
+	}
+
+	result, amount, err := s.GetJokesByText(ctx, 0, 10, "horse", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Horse", "Plain"}, titles(result), "the most relevant joke comes first")
+	assert.Equal(t, 2, amount)
+
+	result, _, err = s.GetJokesByText(ctx, 1, 1, "horse", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Plain"}, titles(result), "pages follow the relevance order")
+}
+
+func testGetJokesByTextPlainIsLiteral(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	for _, text := range []string{".", ".*", "[+?]"} {
+		result, amount, err := s.GetJokesByText(ctx, 0, 10, text, storage.SearchPlain, storage.Filter{})
+		require.NoError(t, err, text)
+		assert.Empty(t, result, "%q is not a pattern", text)
+		assert.Equal(t, 0, amount, text)
+	}
+}
+
+// testGetJokesByTextPlainWords pins the plain syntax down to what every backend implements: all words have to match
+// as prefixes, quotes and OR are no operators and punctuation only separates words.
+func testGetJokesByTextPlainWords(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	_, err := s.AddJoke(ctx, "Fifth joke", "Learning .net and #golang, isn't it über-cool?", 1)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"all words match", "horse bar", []string{"First joke"}},
+		{"words match anywhere", "bar horse", []string{"First joke"}},
+		{"a missing word", "horse penguin", []string{}},
+		{"quotes are no phrase", `"face long"`, []string{"Second joke"}},
+		{"OR is a word", "horse OR bartender", []string{}},
+		{"word after a dot", ".net", []string{"Fifth joke"}},
+		{"word after a hash", "#golang", []string{"Fifth joke"}},
+		{"words joined by an apostrophe", "isn't", []string{"Fifth joke"}},
+		{"words of other scripts", "Über", []string{"Fifth joke"}},
+		{"words joined by a hyphen", "über-cool", []string{"Fifth joke"}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokesByText(ctx, 0, 10, tt.text, storage.SearchPlain, storage.Filter{})
+		require.NoError(t, err, tt.name)
+		assert.ElementsMatch(t, tt.want, titles(result), tt.name)
+		assert.Equal(t, len(tt.want), amount, tt.name)
+	}
+}
+
+func testGetJokesByTextRegex(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"anchored", "^first", []string{"First joke"}},
+		{"any character", "h.rse", []string{"First joke", "Third joke"}},
+		{"optional group", "bar(tender)?", []string{"First joke", "Second joke"}},
+		{"no hits", "^penguin$", []string{}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokesByText(ctx, 0, 10, tt.pattern, storage.SearchRegex, storage.Filter{})
+		require.NoError(t, err, tt.name)
+		assert.NotNil(t, result, tt.name)
+		assert.ElementsMatch(t, tt.want, titles(result), tt.name)
+		assert.Equal(t, len(tt.want), amount, tt.name)
+	}
+}
+
+func testGetJokesByTextInvalidPattern(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	for _, pattern := range []string{"(", "(a+)+$", strings.Repeat("a", 300)} {
+		_, _, err := s.GetJokesByText(ctx, 0, 10, pattern, storage.SearchRegex, storage.Filter{})
+		assert.ErrorIs(t, err, storage.ErrInvalidPattern, pattern)
+	}
+}
+
+func testGetJokesByTextIndex(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	result, amount, err := s.GetJokesByText(ctx, 0, 10, "HORSE", storage.SearchText, storage.Filter{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"First joke", "Third joke"}, titles(result))
+	assert.Equal(t, 2, amount)
+
+	result, amount, err = s.GetJokesByText(ctx, 0, 10, "penguin", storage.SearchText, storage.Filter{})
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+	assert.Equal(t, 0, amount)
+}
+
+func testGetJokesByTextFuzzy(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"exact", "HORSE", []string{"First joke", "Third joke"}},
+		{"transposition", "hrose", []string{"First joke", "Third joke"}},
+		{"missing letter", "bartendr", []string{"Second joke"}},
+		{"every word has to match", "hrose bra", []string{"First joke"}},
+		{"too many typos", "hxrxe", []string{}},
+		{"short words are exact", "ax", []string{}},
+		{"no hits", "penguin", []string{}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokesByText(ctx, 0, 10, tt.text, storage.SearchFuzzy, storage.Filter{})
+		require.NoError(t, err, tt.name)
+		assert.NotNil(t, result, tt.name)
+		assert.ElementsMatch(t, tt.want, titles(result), tt.name)
+		assert.Equal(t, len(tt.want), amount, tt.name)
+	}
+}
+
+func testGetJokesByTextUnknownMode(t *testing.T, s storage.Storage) {
+	seed(t, s)
+
+	_, _, err := s.GetJokesByText(testContext(t), 0, 10, "joke", storage.SearchMode("soundex"), storage.Filter{})
+	assert.ErrorIs(t, err, storage.ErrUnknownSearchMode)
+}
+
+func testGetRandomJokes(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"limit below amount", 2, 2},
+		{"limit equals amount", 4, 4},
+		{"limit above amount", 10, 4},
+		{"zero limit", 0, 0},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetRandomJokes(ctx, 0, tt.limit, 42)
+		require.NoError(t, err, tt.name)
+		assert.NotNil(t, result, tt.name)
+		assert.Len(t, result, tt.want, tt.name)
+		assert.Equal(t, len(jokes), amount, tt.name)
+
+		seen := make(map[string]bool)
+		for _, joke := range result {
+			assert.Contains(t, jokes, joke, "%s: only stored jokes are sampled", tt.name)
+			assert.False(t, seen[joke.ID], "%s: jokes are sampled without replacement", tt.name)
+			seen[joke.ID] = true
+		}
+	}
+
+	first, _, err := s.GetRandomJokes(ctx, 0, 4, 42)
+	require.NoError(t, err)
+	again, _, err := s.GetRandomJokes(ctx, 0, 4, 42)
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "the same seed gives the same jokes")
+
+	sorted, _, err := s.GetJokes(ctx, 0, 4, storage.Filter{Sort: storage.SortRandom, RandSeed: 42})
+	require.NoError(t, err)
+	assert.Equal(t, sorted, first, "random jokes follow the random order of the seed")
+
+	var paged []models.Joke
+	for skip := 0; skip < 4; skip += 3 {
+		page, amount, err := s.GetRandomJokes(ctx, skip, 3, 42)
+		require.NoError(t, err)
+		assert.Equal(t, len(jokes), amount)
+		paged = append(paged, page...)
+	}
+	assert.Equal(t, first, paged, "pages of the same seed make up one permutation")
+}
+
+func testGetFunniestJokes(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	result, amount, err := s.GetFunniestJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Second joke", "Third joke", "Fourth story", "First joke"}, titles(result))
+	assert.Equal(t, 4, amount)
+
+	result, amount, err = s.GetFunniestJokes(ctx, 1, 2, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Third joke", "Fourth story"}, titles(result))
+	assert.Equal(t, 4, amount)
+
+	result, amount, err = s.GetFunniestJokes(ctx, 3, 2, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"First joke"}, titles(result))
+	assert.Equal(t, 4, amount)
+
+	result, amount, err = s.GetFunniestJokes(ctx, 10, 2, storage.Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Equal(t, 4, amount)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func testFilter(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	_, err := s.AddJoke(ctx, "Fifth joke", "", 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		filter storage.Filter
+		want   []string
+	}{
+		{"min score", storage.Filter{MinScore: intPtr(10)}, []string{"Second joke", "Third joke"}},
+		{"max score", storage.Filter{MaxScore: intPtr(7)}, []string{"First joke", "Fourth story", "Fifth joke"}},
+		{"score range", storage.Filter{MinScore: intPtr(7), MaxScore: intPtr(15)}, []string{"Third joke", "Fourth story"}},
+		{"min body length", storage.Filter{MinBodyLength: intPtr(25)}, []string{"Second joke", "Third joke"}},
+		{"max body length", storage.Filter{MaxBodyLength: intPtr(24)}, []string{"First joke", "Fourth story", "Fifth joke"}},
+		{"has body", storage.Filter{HasBody: boolPtr(true)}, []string{"First joke", "Second joke", "Third joke", "Fourth story"}},
+		{"has no body", storage.Filter{HasBody: boolPtr(false)}, []string{"Fifth joke"}},
+		{"combined", storage.Filter{MinScore: intPtr(1), HasBody: boolPtr(true), MaxBodyLength: intPtr(24)}, []string{"First joke", "Fourth story"}},
+		{"nothing", storage.Filter{MinScore: intPtr(100)}, []string{}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokes(ctx, 0, 10, tt.filter)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, titles(result), tt.name)
+		assert.Equal(t, len(tt.want), amount, tt.name)
+	}
+
+	result, amount, err := s.GetFunniestJokes(ctx, 0, 10, storage.Filter{MaxScore: intPtr(15)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Third joke", "Fourth story", "First joke", "Fifth joke"}, titles(result))
+	assert.Equal(t, 4, amount)
+
+	result, amount, err = s.GetFunniestJokes(ctx, 1, 1, storage.Filter{MinScore: intPtr(10)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Third joke"}, titles(result))
+	assert.Equal(t, 2, amount)
+}
+
+func testFilterSearch(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	tests := []struct {
+		name   string
+		text   string
+		mode   storage.SearchMode
+		filter storage.Filter
+		want   []string
+	}{
+		{"title", "joke", storage.SearchPlain, storage.Filter{Field: storage.FieldTitle}, []string{"First joke", "Second joke", "Third joke"}},
+		{"body", "joke", storage.SearchPlain, storage.Filter{Field: storage.FieldBody}, []string{"Fourth story"}},
+		{"score", "joke", storage.SearchPlain, storage.Filter{MinScore: intPtr(10)}, []string{"Second joke", "Third joke"}},
+		{"regex title", "hor.e", storage.SearchRegex, storage.Filter{Field: storage.FieldTitle}, []string{}},
+		{"regex body", "hor.e", storage.SearchRegex, storage.Filter{Field: storage.FieldBody}, []string{"First joke", "Third joke"}},
+		{"text body", "joke", storage.SearchText, storage.Filter{Field: storage.FieldBody}, []string{"Fourth story"}},
+		{"fuzzy body", "hrose", storage.SearchFuzzy, storage.Filter{Field: storage.FieldBody, MaxScore: intPtr(10)}, []string{"First joke"}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokesByText(ctx, 0, 10, tt.text, tt.mode, tt.filter)
+		require.NoError(t, err, tt.name)
+		assert.ElementsMatch(t, tt.want, titles(result), tt.name)
+		assert.Equal(t, len(tt.want), amount, tt.name)
+	}
+}
+
+func after(joke models.Joke) storage.Filter {
+	cursor := storage.CursorAfter(joke)
+	return storage.Filter{After: &cursor}
+}
+
+func testCursor(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	_, err := s.AddJoke(ctx, "Late joke", "Added between the pages", 20)
+	require.NoError(t, err)
+
+	result, amount, err := s.GetJokes(ctx, 0, 10, after(jokes[1]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Third joke", "Fourth story", "Late joke"}, titles(result))
+	assert.Equal(t, 5, amount, "the amount counts the jokes before the cursor")
+
+	result, _, err = s.GetJokes(ctx, 1, 1, after(jokes[0]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Third joke"}, titles(result), "skip counts from the cursor")
+
+	filter := after(jokes[1])
+	filter.MinScore = intPtr(10)
+	result, amount, err = s.GetJokes(ctx, 0, 10, filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Third joke", "Late joke"}, titles(result))
+	assert.Equal(t, 3, amount)
+
+	result, _, err = s.GetJokes(ctx, 0, 10, after(jokes[3]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Late joke"}, titles(result))
+}
+
+func testCursorFunniest(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	// the page of the client ended with the third joke, then the jokes changed.
+	page, _, err := s.GetFunniestJokes(ctx, 0, 2, storage.Filter{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Second joke", "Third joke"}, titles(page))
+
+	_, err = s.IncrementScore(ctx, jokes[0].ID, 100)
+	require.NoError(t, err)
+
+	_, err = s.AddJoke(ctx, "Tied joke", "Scored like the fourth story", 7)
+	require.NoError(t, err)
+
+	result, amount, err := s.GetFunniestJokes(ctx, 0, 10, after(page[1]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Fourth story", "Tied joke"}, titles(result))
+	assert.Equal(t, 5, amount)
+
+	result, _, err = s.GetFunniestJokes(ctx, 0, 10, after(result[0]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tied joke"}, titles(result), "equal scores keep the insertion order")
+}
+
+func testCursorDeletedJoke(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	page, _, err := s.GetJokes(ctx, 0, 2, storage.Filter{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"First joke", "Second joke"}, titles(page))
+
+	funniest, _, err := s.GetFunniestJokes(ctx, 0, 2, storage.Filter{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Second joke", "Third joke"}, titles(funniest))
+
+	// the last jokes of the pages are deleted, before the clients ask for the next pages.
+	require.NoError(t, s.DeleteJoke(ctx, page[1].ID))
+	require.NoError(t, s.DeleteJoke(ctx, funniest[1].ID))
+
+	result, amount, err := s.GetJokes(ctx, 0, 10, after(page[1]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Fourth story"}, titles(result))
+	assert.Equal(t, 2, amount)
+
+	result, _, err = s.GetFunniestJokes(ctx, 0, 10, after(funniest[1]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Fourth story", "First joke"}, titles(result))
+
+	late, err := s.AddJoke(ctx, "Late joke", "Added after the deletes", 0)
+	require.NoError(t, err)
+
+	result, _, err = s.GetJokes(ctx, 0, 10, after(page[1]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Fourth story", "Late joke"}, titles(result))
+
+	// the position of a deleted newest joke is not given to the next joke, which would go before its cursor.
+	require.NoError(t, s.DeleteJoke(ctx, late.ID))
+
+	_, err = s.AddJoke(ctx, "Later joke", "Added after the newest joke was deleted", 0)
+	require.NoError(t, err)
+
+	result, _, err = s.GetJokes(ctx, 0, 10, after(late))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Later joke"}, titles(result))
+}
+
+func testSort(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	_, err := s.AddJoke(ctx, "another joke", "Titles are sorted case-insensitively", 15)
+	require.NoError(t, err)
+
+	tests := []struct {
+		sort storage.Sort
+		want []string
+	}{
+		{storage.SortCreated, []string{"First joke", "Second joke", "Third joke", "Fourth story", "another joke"}},
+		{storage.SortScore, []string{"First joke", "Fourth story", "Third joke", "another joke", "Second joke"}},
+		{storage.SortScoreDesc, []string{"Second joke", "Third joke", "another joke", "Fourth story", "First joke"}},
+		{storage.SortTitle, []string{"another joke", "First joke", "Fourth story", "Second joke", "Third joke"}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokes(ctx, 0, 10, storage.Filter{Sort: tt.sort})
+		require.NoError(t, err, tt.sort)
+		assert.Equal(t, tt.want, titles(result), tt.sort)
+		assert.Equal(t, 5, amount, tt.sort)
+
+		result, _, err = s.GetFunniestJokes(ctx, 0, 10, storage.Filter{Sort: tt.sort})
+		require.NoError(t, err, tt.sort)
+		assert.Equal(t, tt.want, titles(result), "funniest sorted by %s", tt.sort)
+	}
+
+	result, _, err := s.GetJokes(ctx, 1, 2, storage.Filter{Sort: storage.SortTitle, MinScore: intPtr(5)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Fourth story", "Second joke"}, titles(result))
+
+	filter := after(jokes[3])
+	filter.Sort = storage.SortScore
+	result, _, err = s.GetJokes(ctx, 0, 10, filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Third joke", "another joke", "Second joke"}, titles(result))
+
+	_, _, err = s.GetJokes(ctx, 0, 10, storage.Filter{Sort: storage.SortRelevance})
+	assert.ErrorIs(t, err, storage.ErrInvalidSort)
+
+	filter.Sort = storage.SortTitle
+	_, _, err = s.GetFunniestJokes(ctx, 0, 10, filter)
+	assert.ErrorIs(t, err, storage.ErrInvalidCursor)
+}
+
+func testSortRandom(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	random := storage.Filter{Sort: storage.SortRandom, RandSeed: 42}
+
+	all, amount, err := s.GetJokes(ctx, 0, 10, random)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"First joke", "Second joke", "Third joke", "Fourth story"}, titles(all))
+	assert.Equal(t, 4, amount)
+
+	again, _, err := s.GetFunniestJokes(ctx, 0, 10, random)
+	require.NoError(t, err)
+	assert.Equal(t, titles(all), titles(again), "the seed gives the order")
+
+	first, _, err := s.GetJokes(ctx, 0, 2, random)
+	require.NoError(t, err)
+
+	second, _, err := s.GetJokes(ctx, 2, 2, random)
+	require.NoError(t, err)
+	assert.Equal(t, titles(all), append(titles(first), titles(second)...), "pages of the seed do not overlap")
+}
+
+func testSortSearch(t *testing.T, s storage.Storage) {
+	seed(t, s)
+	ctx := testContext(t)
+
+	tests := []struct {
+		mode storage.SearchMode
+		sort storage.Sort
+		want []string
+	}{
+		{storage.SearchPlain, storage.SortScore, []string{"First joke", "Fourth story", "Third joke", "Second joke"}},
+		{storage.SearchText, storage.SortScoreDesc, []string{"Second joke", "Third joke", "Fourth story", "First joke"}},
+		{storage.SearchRegex, storage.SortTitle, []string{"First joke", "Fourth story", "Second joke", "Third joke"}},
+		{storage.SearchFuzzy, storage.SortCreated, []string{"First joke", "Second joke", "Third joke", "Fourth story"}},
+	}
+
+	for _, tt := range tests {
+		result, amount, err := s.GetJokesByText(ctx, 0, 10, "joke", tt.mode, storage.Filter{Sort: tt.sort})
+		require.NoError(t, err, tt.mode)
+		assert.Equal(t, tt.want, titles(result), "%s sorted by %s", tt.mode, tt.sort)
+		assert.Equal(t, 4, amount, tt.mode)
+	}
+
+	result, _, err := s.GetJokesByText(ctx, 0, 10, "horse", storage.SearchPlain, storage.Filter{Sort: storage.SortRelevance})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"First joke", "Third joke"}, titles(result))
+}
+
+func testUpdateJoke(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	updated, err := s.UpdateJoke(ctx, jokes[0].ID, "Updated", "New body")
+	require.NoError(t, err)
+
+	want := jokes[0]
+	want.Title, want.Body = "Updated", "New body"
+	assert.Equal(t, want, updated, "the joke keeps its position")
+
+	result, err := s.GetJokeByID(ctx, jokes[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, result)
+
+	found, _, err := s.GetJokesByText(ctx, 0, 10, "updated", storage.SearchPlain, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []models.Joke{updated}, found, "search sees the new title")
+
+	_, err = s.UpdateJoke(ctx, "does-not-exist", "Title", "Body")
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+}
+
+func testDeleteJoke(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	require.NoError(t, s.DeleteJoke(ctx, jokes[1].ID))
+
+	_, err := s.GetJokeByID(ctx, jokes[1].ID)
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+
+	result, amount, err := s.GetJokes(ctx, 0, 10, storage.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []models.Joke{jokes[0], jokes[2], jokes[3]}, result)
+	assert.Equal(t, 3, amount)
+
+	assert.ErrorIs(t, s.DeleteJoke(ctx, jokes[1].ID), storage.ErrJokeNotFound)
+}
+
+func testIncrementScore(t *testing.T, s storage.Storage) {
+	jokes := seed(t, s)
+	ctx := testContext(t)
+
+	joke, err := s.IncrementScore(ctx, jokes[0].ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, jokes[0].Score+1, joke.Score)
+
+	joke, err = s.IncrementScore(ctx, jokes[0].ID, -5)
+	require.NoError(t, err)
+	assert.Equal(t, jokes[0].Score-4, joke.Score)
+
+	result, err := s.GetJokeByID(ctx, jokes[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, joke, result)
+
+	_, err = s.IncrementScore(ctx, "does-not-exist", 1)
+	assert.ErrorIs(t, err, storage.ErrJokeNotFound)
+}
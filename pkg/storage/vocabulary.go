@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// VocabularyMaxAge bounds the age of a cached vocabulary, so changes made by other processes
+// sharing the database show up in the fuzzy search.
+const VocabularyMaxAge = time.Minute
+
+// Vocabulary caches the terms of a storage for the fuzzy search, so they are not read from all
+// the jokes on every search. The storage invalidates it, when it adds, changes or deletes jokes.
+// The zero value is an empty cache ready to use.
+type Vocabulary struct {
+	// loading lets a single caller read the terms, while the others wait for its result.
+	loading sync.Mutex
+
+	mu         sync.Mutex
+	terms      []string
+	loadedAt   time.Time
+	valid      bool
+	generation uint64
+}
+
+// Terms returns the cached terms or the terms returned by load, which are cached until the
+// next invalidation or for VocabularyMaxAge. The returned slice must not be changed.
+func (v *Vocabulary) Terms(ctx context.Context, load func(ctx context.Context) ([]string, error)) ([]string, error) {
+	if terms, ok := v.cached(); ok {
+		return terms, nil
+	}
+
+	v.loading.Lock()
+	defer v.loading.Unlock()
+
+	if terms, ok := v.cached(); ok {
+		return terms, nil
+	}
+
+	v.mu.Lock()
+	generation := v.generation
+	v.mu.Unlock()
+
+	terms, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// terms loaded before an invalidation might miss its changes, they are used but not cached.
+	if generation == v.generation {
+		v.terms, v.loadedAt, v.valid = terms, time.Now(), true
+	}
+
+	return terms, nil
+}
+
+// Invalidate drops the cached terms, the next search reads them again.
+func (v *Vocabulary) Invalidate() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.generation++
+	v.terms, v.valid = nil, false
+}
+
+func (v *Vocabulary) cached() ([]string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if !v.valid || time.Since(v.loadedAt) > VocabularyMaxAge {
+		return nil, false
+	}
+
+	return v.terms, true
+}
@@ -0,0 +1,62 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVocabulary(t *testing.T) {
+	ctx := context.Background()
+
+	var vocabulary storage.Vocabulary
+	calls := 0
+	terms := []string{"horse"}
+	load := func(context.Context) ([]string, error) {
+		calls++
+		return terms, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := vocabulary.Terms(ctx, load)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"horse"}, result)
+	}
+	assert.Equal(t, 1, calls, "the terms are cached")
+
+	vocabulary.Invalidate()
+	terms = []string{"horse", "bar"}
+
+	result, err := vocabulary.Terms(ctx, load)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"horse", "bar"}, result)
+	assert.Equal(t, 2, calls, "invalidated terms are loaded again")
+
+	vocabulary.Invalidate()
+	result, err = vocabulary.Terms(ctx, func(ctx context.Context) ([]string, error) {
+		// a mutation during the load makes its terms stale.
+		vocabulary.Invalidate()
+		return load(ctx)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"horse", "bar"}, result)
+
+	_, err = vocabulary.Terms(ctx, load)
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls, "terms loaded before an invalidation are not cached")
+
+	vocabulary.Invalidate()
+	failure := errors.New("storage failure")
+	_, err = vocabulary.Terms(ctx, func(context.Context) ([]string, error) {
+		return nil, failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	_, err = vocabulary.Terms(ctx, load)
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls, "failed loads are not cached")
+}
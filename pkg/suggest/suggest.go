@@ -0,0 +1,205 @@
+// Package suggest completes search requests with the titles of the jokes and the popular search terms.
+package suggest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+)
+
+// loadBatch is the number of jokes read from the storage at once by Load.
+const loadBatch = 500
+
+// DefaultMaxTerms is the number of search terms kept by a Suggester made by NewSuggester.
+const DefaultMaxTerms = 10000
+
+// Suggestions holds the completions of a prefix.
+type Suggestions struct {
+	Prefix string   `json:"prefix"`
+	Titles []string `json:"titles"`
+	Terms  []string `json:"terms"`
+}
+
+// Suggester completes prefixes with joke titles, ranked by the number of jokes having them, and with the
+// words of successful search requests, ranked by the number of searches. It is safe for concurrent use.
+type Suggester struct {
+	mu     sync.RWMutex
+	titles *Trie
+	terms  *Trie
+	jokes  map[string]string
+
+	// searches holds the terms with the number of their searches and the last search, see AddQuery.
+	searches map[string]search
+	maxTerms int
+	clock    uint64
+
+	// written holds the ids of the jokes written while Load runs, it is nil otherwise.
+	written map[string]bool
+}
+
+type search struct {
+	count int
+	last  uint64
+}
+
+// NewSuggester creating a new Suggester object, which returns up to limit titles and terms for a prefix
+// and keeps up to DefaultMaxTerms search terms.
+func NewSuggester(limit int) *Suggester {
+	return NewBoundedSuggester(limit, DefaultMaxTerms)
+}
+
+// NewBoundedSuggester creating a new Suggester object, which returns up to limit titles and terms for a prefix
+// and keeps up to maxTerms search terms.
+func NewBoundedSuggester(limit, maxTerms int) *Suggester {
+	return &Suggester{
+		titles:   NewTrie(limit),
+		terms:    NewTrie(limit),
+		jokes:    make(map[string]string),
+		searches: make(map[string]search),
+		maxTerms: maxTerms,
+	}
+}
+
+// Load adds the titles of all jokes of the storage, it pages through them with cursors.
+// The jokes written by PutJoke and RemoveJoke while it runs are skipped, so a batch read before
+// a write does not undo it. Only one Load may run at a time.
+func (s *Suggester) Load(ctx context.Context, st storage.Storage) error {
+	s.mu.Lock()
+	s.written = make(map[string]bool)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.written = nil
+		s.mu.Unlock()
+	}()
+
+	var filter storage.Filter
+
+	for {
+		jokes, _, err := st.GetJokes(ctx, 0, loadBatch, filter)
+		if err != nil {
+			return fmt.Errorf("loading suggestions error: %w", err)
+		}
+
+		s.putLoaded(jokes)
+
+		if len(jokes) < loadBatch {
+			return nil
+		}
+
+		after := storage.CursorAfter(jokes[len(jokes)-1])
+		filter.After = &after
+	}
+}
+
+// putLoaded adds the titles of a batch of Load, which skips the jokes written since the load started.
+func (s *Suggester) putLoaded(jokes []models.Joke) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, joke := range jokes {
+		if !s.written[joke.ID] {
+			s.put(joke)
+		}
+	}
+}
+
+// PutJoke adds the title of the joke or replaces the title of the joke with the same id.
+func (s *Suggester) PutJoke(joke models.Joke) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(joke)
+	s.markWritten(joke.ID)
+}
+
+func (s *Suggester) put(joke models.Joke) {
+	if title, ok := s.jokes[joke.ID]; ok {
+		s.titles.Add(title, -1)
+	}
+
+	s.jokes[joke.ID] = joke.Title
+	s.titles.Add(joke.Title, 1)
+}
+
+// markWritten records the write of the joke with the id for a running Load.
+func (s *Suggester) markWritten(id string) {
+	if s.written != nil {
+		s.written[id] = true
+	}
+}
+
+// RemoveJoke removes the title of the joke with the id.
+func (s *Suggester) RemoveJoke(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.markWritten(id)
+
+	if title, ok := s.jokes[id]; ok {
+		s.titles.Add(title, -1)
+		delete(s.jokes, id)
+	}
+}
+
+// AddQuery counts the words of a search request, which found jokes. When there are more than
+// the maximum number of terms, the least searched terms are dropped, the least recently searched first.
+func (s *Suggester) AddQuery(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, word := range storage.Words(text) {
+		s.clock++
+		s.searches[word] = search{count: s.searches[word].count + 1, last: s.clock}
+		s.terms.Add(word, 1)
+	}
+
+	if len(s.searches) > s.maxTerms {
+		s.prune()
+	}
+}
+
+// prune drops the least searched terms down to three quarters of the maximum, so it runs once
+// in many searches adding new terms.
+func (s *Suggester) prune() {
+	words := make([]string, 0, len(s.searches))
+	for word := range s.searches {
+		words = append(words, word)
+	}
+
+	sort.Slice(words, func(i, j int) bool {
+		a, b := s.searches[words[i]], s.searches[words[j]]
+		if a.count != b.count {
+			return a.count < b.count
+		}
+		return a.last < b.last
+	})
+
+	for _, word := range words[:len(words)-s.maxTerms*3/4] {
+		s.terms.Add(word, -s.searches[word].count)
+		delete(s.searches, word)
+	}
+}
+
+// Suggest returns up to n titles starting with prefix and up to n terms starting with the last word
+// of prefix, which is the word being typed. Both are matched case-insensitively.
+func (s *Suggester) Suggest(prefix string, n int) Suggestions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := []string{}
+	if words := storage.Words(prefix); len(words) > 0 {
+		terms = s.terms.Top(words[len(words)-1], n)
+	}
+
+	return Suggestions{
+		Prefix: prefix,
+		Titles: s.titles.Top(prefix, n),
+		Terms:  terms,
+	}
+}
@@ -0,0 +1,130 @@
+package suggest_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
+	fs "github.com/DanilLagunov/jokes-api/pkg/storage/file-storage"
+	"github.com/DanilLagunov/jokes-api/pkg/suggest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggester(t *testing.T) {
+	s := suggest.NewSuggester(5)
+
+	s.PutJoke(models.NewJoke("a", "Horse walks into a bar", "", 0))
+	s.PutJoke(models.NewJoke("b", "Horse walks into a bar", "", 0))
+	s.PutJoke(models.NewJoke("c", "Hockey night", "", 0))
+	s.AddQuery("horse")
+	s.AddQuery("Horse bar")
+	s.AddQuery("hockey")
+
+	assert.Equal(t, suggest.Suggestions{
+		Prefix: "ho",
+		Titles: []string{"Horse walks into a bar", "Hockey night"},
+		Terms:  []string{"horse", "hockey"},
+	}, s.Suggest("ho", 5))
+
+	assert.Equal(t, []string{"bar"}, s.Suggest("horse b", 5).Terms, "terms complete the last word")
+
+	s.PutJoke(models.NewJoke("a", "Cow walks into a bar", "", 0))
+	s.RemoveJoke("b")
+	s.RemoveJoke("unknown")
+
+	assert.Equal(t, []string{"Hockey night"}, s.Suggest("ho", 5).Titles)
+	assert.Equal(t, []string{"Cow walks into a bar"}, s.Suggest("c", 5).Titles)
+}
+
+func TestSuggesterLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jokes.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0o600))
+
+	storage, err := fs.NewFileStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+
+	for i := 0; i < 510; i++ {
+		_, err := storage.AddJoke(context.Background(), fmt.Sprintf("Joke %d", i), "Body", 0)
+		require.NoError(t, err)
+	}
+
+	s := suggest.NewSuggester(5)
+	require.NoError(t, s.Load(context.Background(), storage))
+
+	assert.Equal(t, []string{"Joke 509"}, s.Suggest("joke 509", 5).Titles, "every page is loaded")
+	assert.Len(t, s.Suggest("joke", 5).Titles, 5)
+}
+
+// staleStorage runs write after reading every batch, like a write racing with the load of the batch.
+type staleStorage struct {
+	storage.Storage
+	write func()
+	skips []int
+}
+
+func (s *staleStorage) GetJokes(ctx context.Context, skip, limit int, filter storage.Filter) ([]models.Joke, int, error) {
+	jokes, amount, err := s.Storage.GetJokes(ctx, skip, limit, filter)
+	s.skips = append(s.skips, skip)
+	s.write()
+
+	return jokes, amount, err
+}
+
+func TestSuggesterLoadSkipsWrittenJokes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jokes.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0o600))
+
+	fileStorage, err := fs.NewFileStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { fileStorage.Close() })
+
+	horse, err := fileStorage.AddJoke(context.Background(), "Horse joke", "Body", 0)
+	require.NoError(t, err)
+	cow, err := fileStorage.AddJoke(context.Background(), "Cow joke", "Body", 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 510; i++ {
+		_, err := fileStorage.AddJoke(context.Background(), fmt.Sprintf("Joke %d", i), "Body", 0)
+		require.NoError(t, err)
+	}
+
+	s := suggest.NewSuggester(5)
+
+	st := &staleStorage{Storage: fileStorage}
+	st.write = func() {
+		if len(st.skips) == 1 {
+			s.PutJoke(models.NewJoke(horse.ID, "Updated joke", "Body", 0))
+			s.RemoveJoke(cow.ID)
+		}
+	}
+
+	require.NoError(t, s.Load(context.Background(), st))
+
+	assert.Equal(t, []string{"Updated joke"}, s.Suggest("updated", 5).Titles, "an update is not undone by the batch read before it")
+	assert.Empty(t, s.Suggest("horse", 5).Titles)
+	assert.Empty(t, s.Suggest("cow", 5).Titles, "a delete is not undone by the batch read before it")
+	assert.Equal(t, []string{"Joke 509"}, s.Suggest("joke 509", 5).Titles, "every batch is loaded")
+	assert.Equal(t, []int{0, 0}, st.skips, "the batches follow each other by cursors")
+
+	s.PutJoke(models.NewJoke(horse.ID, "Horse joke", "Body", 0))
+	assert.Equal(t, []string{"Horse joke"}, s.Suggest("horse", 5).Titles, "writes after the load apply")
+}
+
+func TestSuggesterMaxTerms(t *testing.T) {
+	s := suggest.NewBoundedSuggester(5, 4)
+
+	s.AddQuery("horse horse horse")
+	s.AddQuery("hockey hockey")
+	s.AddQuery("hoof")
+	s.AddQuery("hook")
+	s.AddQuery("hose")
+
+	assert.Equal(t, []string{"horse", "hockey", "hose"}, s.Suggest("ho", 5).Terms,
+		"the least searched terms are dropped, the least recently searched first")
+}
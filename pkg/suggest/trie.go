@@ -0,0 +1,126 @@
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// Trie is a prefix tree of weighted keys. Keys are matched case-insensitively and returned as they were
+// first added. Every node keeps the heaviest keys below it, so a lookup only walks down the prefix.
+// A Trie is not safe for concurrent writes, reads may run concurrently with each other.
+type Trie struct {
+	root  *node
+	limit int
+}
+
+type node struct {
+	children map[rune]*node
+	key      string
+	weight   int
+	// top holds up to limit of the heaviest keys in the subtree, heaviest first.
+	top []*node
+}
+
+// NewTrie creating a new Trie object, which returns up to limit keys for a prefix.
+func NewTrie(limit int) *Trie {
+	return &Trie{root: &node{}, limit: limit}
+}
+
+// Add adds weight to the key, a key reaching a weight of zero or less is removed.
+func (t *Trie) Add(key string, weight int) {
+	path := []*node{t.root}
+	runes := []rune(strings.ToLower(key))
+
+	n := t.root
+	for _, r := range runes {
+		child, ok := n.children[r]
+		if !ok {
+			if weight <= 0 {
+				return
+			}
+
+			child = &node{}
+			if n.children == nil {
+				n.children = make(map[rune]*node)
+			}
+			n.children[r] = child
+		}
+
+		n = child
+		path = append(path, n)
+	}
+
+	if n.weight == 0 {
+		n.key = key
+	}
+
+	n.weight += weight
+	if n.weight <= 0 {
+		n.weight = 0
+		n.key = ""
+	}
+
+	// the top keys change only along the path, they are rebuilt from the deepest node up.
+	for i := len(path) - 1; i >= 0; i-- {
+		t.rebuild(path[i])
+
+		// nodes left without keys are dropped, runes[i-1] leads from path[i-1] to path[i].
+		if i > 0 && path[i].weight == 0 && len(path[i].children) == 0 {
+			delete(path[i-1].children, runes[i-1])
+		}
+	}
+}
+
+// Top returns up to n of the heaviest keys starting with prefix, heavier and then alphabetically smaller keys first.
+func (t *Trie) Top(prefix string, n int) []string {
+	node := t.root
+	for _, r := range strings.ToLower(prefix) {
+		if node = node.children[r]; node == nil {
+			return []string{}
+		}
+	}
+
+	if n > len(node.top) {
+		n = len(node.top)
+	}
+
+	keys := make([]string, 0, n)
+	for _, top := range node.top[:n] {
+		keys = append(keys, top.key)
+	}
+	return keys
+}
+
+// Weight returns the weight of the key, zero if the trie does not contain it.
+func (t *Trie) Weight(key string) int {
+	n := t.root
+	for _, r := range strings.ToLower(key) {
+		if n = n.children[r]; n == nil {
+			return 0
+		}
+	}
+	return n.weight
+}
+
+// rebuild merges the top keys of the children and the key of the node itself.
+func (t *Trie) rebuild(n *node) {
+	var candidates []*node
+	if n.weight > 0 {
+		candidates = append(candidates, n)
+	}
+	for _, child := range n.children {
+		candidates = append(candidates, child.top...)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].weight != candidates[j].weight {
+			return candidates[i].weight > candidates[j].weight
+		}
+		return candidates[i].key < candidates[j].key
+	})
+
+	if len(candidates) > t.limit {
+		candidates = candidates[:t.limit]
+	}
+	n.top = candidates
+}
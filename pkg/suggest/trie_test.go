@@ -0,0 +1,47 @@
+package suggest_test
+
+import (
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/suggest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrie(t *testing.T) {
+	trie := suggest.NewTrie(3)
+
+	trie.Add("Horse", 1)
+	trie.Add("horseman", 3)
+	trie.Add("house", 2)
+	trie.Add("hockey", 2)
+	trie.Add("cow", 5)
+
+	assert.Equal(t, []string{"cow", "horseman", "hockey"}, trie.Top("", 5), "the limit bounds the keys")
+	assert.Equal(t, []string{"horseman", "hockey", "house"}, trie.Top("H", 5))
+	assert.Equal(t, []string{"horseman", "Horse"}, trie.Top("hors", 5), "keys keep their case")
+	assert.Equal(t, []string{"horseman"}, trie.Top("hors", 1))
+	assert.Equal(t, []string{}, trie.Top("pig", 5))
+
+	trie.Add("HORSE", 4)
+	assert.Equal(t, 5, trie.Weight("horse"), "keys are case-insensitive")
+	assert.Equal(t, []string{"Horse", "horseman", "hockey"}, trie.Top("h", 5))
+}
+
+func TestTrieRemove(t *testing.T) {
+	trie := suggest.NewTrie(2)
+
+	trie.Add("horse", 3)
+	trie.Add("horseman", 2)
+	trie.Add("house", 1)
+
+	trie.Add("horse", -3)
+	assert.Equal(t, 0, trie.Weight("horse"))
+	assert.Equal(t, []string{"horseman", "house"}, trie.Top("ho", 5), "lighter keys move up")
+
+	trie.Add("horseman", -5)
+	assert.Equal(t, []string{}, trie.Top("hors", 5))
+	assert.Equal(t, []string{"house"}, trie.Top("", 5))
+
+	trie.Add("pig", -1)
+	assert.Equal(t, 0, trie.Weight("pig"), "missing keys are not added with a negative weight")
+}
@@ -0,0 +1,209 @@
+package views
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// snippetLength is the number of runes of the body shown around the first match.
+const snippetLength = 200
+
+// snippetLead is the number of runes of the body shown before the first match.
+const snippetLead = 60
+
+const ellipsis = "…"
+
+// Fragment is a part of a highlighted text, Match marks the words that matched the search request.
+type Fragment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// Highlight holds the highlighted title and a highlighted snippet of the body of a found joke.
+type Highlight struct {
+	Title []Fragment `json:"title"`
+	Body  []Fragment `json:"body"`
+}
+
+// Highlights returns the highlight of every joke by its id. Words of the text highlight every word
+// they are a case-insensitive prefix of, the search syntax characters are ignored.
+func Highlights(text string, jokes []models.Joke) map[string]Highlight {
+	return highlightAll(jokes, wordMatcher(searchTerms(text)))
+}
+
+// PatternHighlights returns the highlight of every joke by its id, every match of re is highlighted.
+func PatternHighlights(re *regexp.Regexp, jokes []models.Joke) map[string]Highlight {
+	return highlightAll(jokes, patternMatcher(re))
+}
+
+// span is a match in a text given in runes.
+type span struct {
+	start, end int
+}
+
+// matcher returns the ordered, non-overlapping matches in a text.
+type matcher func(text []rune) []span
+
+func highlightAll(jokes []models.Joke, match matcher) map[string]Highlight {
+	result := make(map[string]Highlight, len(jokes))
+	for _, joke := range jokes {
+		title := []rune(joke.Title)
+
+		result[joke.ID] = Highlight{
+			Title: highlight(title, match(title)),
+			Body:  snippet([]rune(joke.Body), match),
+		}
+	}
+	return result
+}
+
+func searchTerms(text string) []string {
+	var terms []string
+
+	for _, word := range strings.FieldsFunc(text, isSeparator) {
+		if word != "OR" {
+			terms = append(terms, strings.ToLower(word))
+		}
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// wordMatcher matches the words, which start with one of the terms.
+func wordMatcher(terms []string) matcher {
+	return func(text []rune) []span {
+		var spans []span
+
+		start := 0
+		for i := 0; i <= len(text); i++ {
+			if i < len(text) && !isSeparator(text[i]) {
+				continue
+			}
+
+			if i > start && matches(string(text[start:i]), terms) {
+				spans = append(spans, span{start, i})
+			}
+			start = i + 1
+		}
+		return spans
+	}
+}
+
+func matches(word string, terms []string) bool {
+	word = strings.ToLower(word)
+
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternMatcher matches re, empty matches are skipped.
+func patternMatcher(re *regexp.Regexp) matcher {
+	return func(text []rune) []span {
+		s := string(text)
+
+		var spans []span
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			if loc[0] < loc[1] {
+				// the locations are byte offsets.
+				spans = append(spans, span{utf8.RuneCountInString(s[:loc[0]]), utf8.RuneCountInString(s[:loc[1]])})
+			}
+		}
+		return spans
+	}
+}
+
+// highlight splits text into fragments, every match is a fragment of its own.
+func highlight(text []rune, spans []span) []Fragment {
+	fragments := []Fragment{}
+
+	add := func(s string, match bool) {
+		if s == "" {
+			return
+		}
+
+		if last := len(fragments) - 1; last >= 0 && !match && !fragments[last].Match {
+			fragments[last].Text += s
+			return
+		}
+		fragments = append(fragments, Fragment{Text: s, Match: match})
+	}
+
+	pos := 0
+	for _, sp := range spans {
+		add(string(text[pos:sp.start]), false)
+		add(string(text[sp.start:sp.end]), true)
+		pos = sp.end
+	}
+	add(string(text[pos:]), false)
+
+	return fragments
+}
+
+// snippet highlights the part of the body around its first match. Long bodies are cut at word
+// boundaries, which is marked with an ellipsis.
+func snippet(body []rune, match matcher) []Fragment {
+	spans := match(body)
+
+	if len(body) <= snippetLength {
+		return highlight(body, spans)
+	}
+
+	start := -snippetLead
+	if len(spans) > 0 {
+		start += spans[0].start
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	end := start + snippetLength
+	if end > len(body) {
+		end = len(body)
+		start = end - snippetLength
+	}
+
+	// move the cuts inside to the closest word boundaries.
+	for start > 0 && start < end && !isSeparator(body[start-1]) {
+		start++
+	}
+	for end < len(body) && end > start && !isSeparator(body[end]) {
+		end--
+	}
+
+	text := append([]rune{}, body[start:end]...)
+
+	offset := -start
+	if start > 0 {
+		text = append([]rune(ellipsis), text...)
+		offset += len([]rune(ellipsis))
+	}
+	if end < len(body) {
+		text = append(text, []rune(ellipsis)...)
+	}
+
+	// keep the parts of the matches inside the snippet.
+	var clipped []span
+	for _, sp := range spans {
+		if sp.start < start {
+			sp.start = start
+		}
+		if sp.end > end {
+			sp.end = end
+		}
+		if sp.start < sp.end {
+			clipped = append(clipped, span{sp.start + offset, sp.end + offset})
+		}
+	}
+	return highlight(text, clipped)
+}
@@ -0,0 +1,77 @@
+package views_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlights(t *testing.T) {
+	joke := models.NewJoke("a", "Horses and a horseman", "A horse, of course!", 0)
+
+	highlights := views.Highlights(`HORSE OR "cow"`, []models.Joke{joke})
+	require.Contains(t, highlights, "a")
+
+	assert.Equal(t, []views.Fragment{
+		{Text: "Horses", Match: true},
+		{Text: " and a "},
+		{Text: "horseman", Match: true},
+	}, highlights["a"].Title)
+
+	assert.Equal(t, []views.Fragment{
+		{Text: "A "},
+		{Text: "horse", Match: true},
+		{Text: ", of course!"},
+	}, highlights["a"].Body)
+}
+
+func TestHighlightsSnippet(t *testing.T) {
+	body := strings.Repeat("blah ", 100) + "the punchline " + strings.Repeat("blah ", 100)
+	joke := models.NewJoke("a", "Long", body, 0)
+
+	fragments := views.Highlights("punchline", []models.Joke{joke})["a"].Body
+	require.Len(t, fragments, 3)
+
+	assert.True(t, strings.HasPrefix(fragments[0].Text, "…blah"), "the cut is marked and on a word boundary")
+	assert.Equal(t, views.Fragment{Text: "punchline", Match: true}, fragments[1])
+	assert.True(t, strings.HasSuffix(fragments[2].Text, "blah…"), "the cut is marked and on a word boundary")
+
+	length := 0
+	for _, f := range fragments {
+		length += len([]rune(f.Text))
+	}
+	assert.LessOrEqual(t, length, 202)
+}
+
+func TestHighlightsWithoutMatch(t *testing.T) {
+	joke := models.NewJoke("a", "Title", "Body", 0)
+
+	highlights := views.Highlights("penguin", []models.Joke{joke})
+
+	assert.Equal(t, []views.Fragment{{Text: "Title"}}, highlights["a"].Title)
+	assert.Equal(t, []views.Fragment{{Text: "Body"}}, highlights["a"].Body)
+}
+
+func TestPatternHighlights(t *testing.T) {
+	joke := models.NewJoke("a", "Ünïcode horse", "A hörse and a horse", 0)
+
+	highlights := views.PatternHighlights(regexp.MustCompile("(?i)h.rse|x*"), []models.Joke{joke})
+	require.Contains(t, highlights, "a")
+
+	assert.Equal(t, []views.Fragment{
+		{Text: "Ünïcode "},
+		{Text: "horse", Match: true},
+	}, highlights["a"].Title)
+
+	assert.Equal(t, []views.Fragment{
+		{Text: "A "},
+		{Text: "hörse", Match: true},
+		{Text: " and a "},
+		{Text: "horse", Match: true},
+	}, highlights["a"].Body)
+}
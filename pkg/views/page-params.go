@@ -1,24 +1,32 @@
 package views
 
 import (
+	"html/template"
+
 	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/storage"
 )
 
-// JokesPageParams struct.
+// JokesPageParams struct. Filters holds the encoded filter and sort parameters the page links have to keep,
+// Cursor continues the listing after the page, it is empty on the last page. RandSeed is the seed of
+// a random order, which clients send back as rand_seed to get the other pages of the same order.
 type JokesPageParams struct {
-	Skip     int
-	Seed     int
-	CurrPage int
-	MaxPage  int
-	Content  []models.Joke
-	Next     int
-	Prev     int
+	Skip     int           `json:"skip"`
+	Seed     int           `json:"seed"`
+	CurrPage int           `json:"current_page"`
+	MaxPage  int           `json:"max_page"`
+	Content  []models.Joke `json:"jokes"`
+	Next     int           `json:"next"`
+	Prev     int           `json:"prev"`
+	Cursor   string        `json:"cursor,omitempty"`
+	RandSeed *int64        `json:"rand_seed,omitempty"`
+	Filters  template.URL  `json:"-"`
 }
 
 // CreatePageParams creating a new JokesPageParams object.
 func CreatePageParams(skip, limit, amount int, content []models.Joke) JokesPageParams {
 	if skip >= amount || limit == 0 {
-		return JokesPageParams{skip, limit, 0, 0, []models.Joke{}, 0, 0}
+		return JokesPageParams{skip, limit, 0, 0, []models.Joke{}, 0, 0, "", nil, ""}
 	}
 
 	currPage := skip/limit + 1
@@ -32,15 +40,20 @@ func CreatePageParams(skip, limit, amount int, content []models.Joke) JokesPageP
 		maxPage = amount / limit
 	}
 
-	if skip+limit >= amount {
-		return JokesPageParams{skip, limit, currPage, maxPage, content[:amount-skip], next, prev}
+	// the content is cut to the amount, pages continuing a cursor can already be shorter.
+	if n := amount - skip; skip+limit >= amount && n < len(content) {
+		return JokesPageParams{skip, limit, currPage, maxPage, content[:n], next, prev, "", nil, ""}
 	}
 
-	return JokesPageParams{skip, limit, currPage, maxPage, content, next, prev}
+	return JokesPageParams{skip, limit, currPage, maxPage, content, next, prev, "", nil, ""}
 }
 
-// SearchPageParams struct.
+// SearchPageParams struct. Highlights holds the highlight of every joke of the page by its id,
+// Suggestions holds corrections of the search request, when nothing was found.
 type SearchPageParams struct {
-	SearchRequest string
-	PageParams    JokesPageParams
+	SearchRequest string               `json:"search_request"`
+	Mode          storage.SearchMode   `json:"mode"`
+	PageParams    JokesPageParams      `json:"page"`
+	Highlights    map[string]Highlight `json:"highlights"`
+	Suggestions   []string             `json:"suggestions,omitempty"`
 }
@@ -15,10 +15,10 @@ func TestCreatePageParams(t *testing.T) {
 		SrcSeed  int
 		Expected views.JokesPageParams
 	}{
-		{0, 0, views.JokesPageParams{0, 0, 0, 0, []models.Joke{}, 0, 0}},
-		{20, 20, views.JokesPageParams{20, 20, 2, 5, content[:], 40, 0}},
-		{99, 20, views.JokesPageParams{99, 20, 5, 5, content[:1], 119, 79}},
-		{101, 20, views.JokesPageParams{101, 20, 0, 0, []models.Joke{}, 0, 0}},
+		{0, 0, views.JokesPageParams{0, 0, 0, 0, []models.Joke{}, 0, 0, "", nil, ""}},
+		{20, 20, views.JokesPageParams{20, 20, 2, 5, content[:], 40, 0, "", nil, ""}},
+		{99, 20, views.JokesPageParams{99, 20, 5, 5, content[:1], 119, 79, "", nil, ""}},
+		{101, 20, views.JokesPageParams{101, 20, 0, 0, []models.Joke{}, 0, 0, "", nil, ""}},
 	}
 
 	for _, tc := range tests {
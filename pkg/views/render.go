@@ -0,0 +1,251 @@
+package views
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+)
+
+// Format describes the representation a response is rendered in.
+type Format string
+
+const (
+	// FormatHTML renders the response with the html templates.
+	FormatHTML Format = "html"
+	// FormatJSON renders the response as a JSON document.
+	FormatJSON Format = "json"
+	// FormatNDJSON renders the jokes of the response as newline delimited JSON.
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV renders the jokes of the response as CSV.
+	FormatCSV Format = "csv"
+)
+
+// ErrUnsupportedFormat describes the error when the requested format is not supported.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+const problemContentType = "application/problem+json; charset=utf-8"
+
+var contentTypes = map[Format]string{
+	FormatHTML:   "text/html; charset=utf-8",
+	FormatJSON:   "application/json; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson; charset=utf-8",
+	FormatCSV:    "text/csv; charset=utf-8",
+}
+
+var mediaTypes = map[string]Format{
+	"text/html":             FormatHTML,
+	"application/xhtml+xml": FormatHTML,
+	"application/json":      FormatJSON,
+	"application/x-ndjson":  FormatNDJSON,
+	"text/csv":              FormatCSV,
+}
+
+type formatKey struct{}
+
+// WithFormat returns a copy of ctx that forces every response to be rendered in the given format.
+func WithFormat(ctx context.Context, f Format) context.Context {
+	return context.WithValue(ctx, formatKey{}, f)
+}
+
+// NegotiateFormat picks the response format from the request context, the format query parameter
+// or the Accept header, in that order. HTML is used when nothing else is requested.
+func NegotiateFormat(r *http.Request) (Format, error) {
+	if f, ok := r.Context().Value(formatKey{}).(Format); ok {
+		return f, nil
+	}
+
+	if param := r.URL.Query().Get("format"); param != "" {
+		f := Format(strings.ToLower(param))
+		if _, ok := contentTypes[f]; !ok {
+			return FormatHTML, fmt.Errorf("%w: %s", ErrUnsupportedFormat, param)
+		}
+
+		return f, nil
+	}
+
+	return parseAccept(r.Header.Get("Accept")), nil
+}
+
+type mediaRange struct {
+	format Format
+	q      float64
+}
+
+func parseAccept(accept string) Format {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		f, ok := mediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, mediaRange{f, q})
+		}
+	}
+
+	if len(ranges) == 0 {
+		return FormatHTML
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	return ranges[0].format
+}
+
+// Render writes data in the format negotiated for the request. The name is the html template
+// used for html clients, the other formats are produced from the data itself.
+func (t Template) Render(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) {
+	format, err := NegotiateFormat(r)
+	if err != nil {
+		t.RenderProblem(w, r, NewProblem(http.StatusNotAcceptable, "not_acceptable", err.Error()))
+		return
+	}
+
+	var buf bytes.Buffer
+
+	switch format {
+	case FormatHTML:
+		err = t.Template.ExecuteTemplate(&buf, name, data)
+	case FormatJSON:
+		err = json.NewEncoder(&buf).Encode(data)
+	case FormatNDJSON:
+		err = encodeNDJSON(&buf, jokesOf(data))
+	case FormatCSV:
+		err = encodeCSV(&buf, jokesOf(data))
+	}
+
+	if err != nil {
+		log.Printf("rendering error: %s", err)
+		t.RenderProblem(w, r, NewProblem(http.StatusInternalServerError, "render_failure", "the response could not be rendered"))
+
+		return
+	}
+
+	write(w, contentTypes[format], status, buf.Bytes())
+}
+
+// Problem describes an error response as defined by RFC 7807, extended with a stable error code.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+// NewProblem creating a new Problem object.
+func NewProblem(status int, code, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// RenderProblem writes the problem as problem+json for API clients and as the error page for html clients.
+func (t Template) RenderProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	format, err := NegotiateFormat(r)
+	if err != nil {
+		format = FormatJSON
+	}
+
+	if format == FormatHTML {
+		var buf bytes.Buffer
+
+		if err := t.Template.ExecuteTemplate(&buf, ErrorTemplate, p); err == nil {
+			write(w, contentTypes[FormatHTML], p.Status, buf.Bytes())
+			return
+		}
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, p.Detail, p.Status)
+		return
+	}
+
+	write(w, problemContentType, p.Status, append(body, '\n'))
+}
+
+func write(w http.ResponseWriter, contentType string, status int, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+
+	if _, err := w.Write(body); err != nil {
+		log.Printf("response writing error: %s", err)
+	}
+}
+
+func jokesOf(data interface{}) []models.Joke {
+	switch v := data.(type) {
+	case models.Joke:
+		return []models.Joke{v}
+	case JokesPageParams:
+		return v.Content
+	case SearchPageParams:
+		return v.PageParams.Content
+	default:
+		return nil
+	}
+}
+
+func encodeNDJSON(buf *bytes.Buffer, jokes []models.Joke) error {
+	encoder := json.NewEncoder(buf)
+
+	for _, joke := range jokes {
+		if err := encoder.Encode(joke); err != nil {
+			return fmt.Errorf("encoding error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func encodeCSV(buf *bytes.Buffer, jokes []models.Joke) error {
+	writer := csv.NewWriter(buf)
+
+	if err := writer.Write([]string{"id", "title", "body", "score"}); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+
+	for _, joke := range jokes {
+		if err := writer.Write([]string{joke.ID, joke.Title, joke.Body, strconv.Itoa(joke.Score)}); err != nil {
+			return fmt.Errorf("encoding error: %w", err)
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,94 @@
+package views_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DanilLagunov/jokes-api/pkg/models"
+	"github.com/DanilLagunov/jokes-api/pkg/views"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		URL      string
+		Accept   string
+		Expected views.Format
+		Valid    bool
+	}{
+		{"/jokes", "", views.FormatHTML, true},
+		{"/jokes", "text/html,application/xhtml+xml,*/*;q=0.8", views.FormatHTML, true},
+		{"/jokes", "application/json", views.FormatJSON, true},
+		{"/jokes", "text/html;q=0.5, application/x-ndjson", views.FormatNDJSON, true},
+		{"/jokes", "image/png, text/csv;q=0.1", views.FormatCSV, true},
+		{"/jokes?format=csv", "application/json", views.FormatCSV, true},
+		{"/jokes?format=JSON", "", views.FormatJSON, true},
+		{"/jokes?format=xml", "", views.FormatHTML, false},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, tc.URL, nil)
+		req.Header.Set("Accept", tc.Accept)
+
+		format, err := views.NegotiateFormat(req)
+		if tc.Valid {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, views.ErrUnsupportedFormat)
+		}
+		assert.EqualValues(t, tc.Expected, format, tc.URL+" "+tc.Accept)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/jokes?format=csv", nil)
+	req = req.WithContext(views.WithFormat(context.Background(), views.FormatJSON))
+
+	format, err := views.NegotiateFormat(req)
+	require.NoError(t, err)
+	assert.EqualValues(t, views.FormatJSON, format)
+}
+
+func TestRender(t *testing.T) {
+	template := views.NewTemptale("../../templates/")
+	page := views.JokesPageParams{Content: []models.Joke{
+		{ID: "1", Title: "First", Body: "Comma, \"quoted\"", Score: 3},
+		{ID: "2", Title: "Second", Body: "second", Score: 5},
+	}}
+
+	tests := []struct {
+		Format      string
+		ContentType string
+		Expected    string
+	}{
+		{
+			Format:      "csv",
+			ContentType: "text/csv; charset=utf-8",
+			Expected:    "id,title,body,score\n1,First,\"Comma, \"\"quoted\"\"\",3\n2,Second,second,5\n",
+		},
+		{
+			Format:      "ndjson",
+			ContentType: "application/x-ndjson; charset=utf-8",
+			Expected: `{"id":"1","title":"First","body":"Comma, \"quoted\"","score":3}` + "\n" +
+				`{"id":"2","title":"Second","body":"second","score":5}` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/jokes?format="+tc.Format, nil)
+
+		template.Render(recorder, req, http.StatusOK, views.GetJokesTemplate, page)
+
+		assert.EqualValues(t, http.StatusOK, recorder.Code)
+		assert.EqualValues(t, tc.ContentType, recorder.Header().Get("Content-Type"))
+		assert.EqualValues(t, tc.Expected, recorder.Body.String())
+	}
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/jokes?format=xml", nil)
+
+	template.Render(recorder, req, http.StatusOK, views.GetJokesTemplate, page)
+	assert.EqualValues(t, http.StatusNotAcceptable, recorder.Code)
+}
@@ -21,6 +21,9 @@ const GetRandomJokesTemplate string = "random"
 // GetFunniestJokesTemplate is a constant for calling the "funniest" template.
 const GetFunniestJokesTemplate string = "funniest"
 
+// ErrorTemplate is a constant for calling the "error" template.
+const ErrorTemplate string = "error"
+
 // Template struct.
 type Template struct {
 	Template *template.Template
@@ -36,6 +39,7 @@ func NewTemptale(folder string) Template {
 		path.Join(folder, "get-jokes-by-text.html"),
 		path.Join(folder, "random.html"),
 		path.Join(folder, "funniest.html"),
+		path.Join(folder, "error.html"),
 		path.Join(folder, "header.html"),
 		path.Join(folder, "footer.html"))
 	if err != nil {